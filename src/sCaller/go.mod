@@ -5,11 +5,13 @@ go 1.22.0
 require (
 	github.com/BurntSushi/toml v1.3.2
 	github.com/cosmos/go-bip39 v1.0.0
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.0
 	github.com/spf13/cobra v1.8.0
+	golang.org/x/crypto v0.33.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/crypto v0.0.0-20200728195943-123391ffb6de // indirect
+	golang.org/x/sys v0.30.0 // indirect
 )
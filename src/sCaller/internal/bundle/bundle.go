@@ -0,0 +1,499 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"scaller/internal/config"
+	"scaller/internal/node"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ManifestName is the first entry of every bundle
+const ManifestName = "manifest.json"
+
+// FormatVersion is the bundle layout written by this version of scaller
+const FormatVersion = 1
+
+// scrypt parameters for the bundle key
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Bounds of the scrypt parameters accepted from a manifest, which could
+// otherwise ask for any amount of memory and time
+const (
+	maxScryptN = 1 << 20
+	maxScryptR = 16
+	maxScryptP = 4
+)
+
+// Manifest describes the contents of a bundle
+type Manifest struct {
+	Version          int         `json:"version"`
+	CreatedAt        time.Time   `json:"created_at"`
+	Hostname         string      `json:"hostname"`
+	ChainID          string      `json:"chain_id,omitempty"`
+	NodeID           string      `json:"node_id,omitempty"`
+	ValidatorAddress string      `json:"validator_address,omitempty"`
+	Encryption       *Encryption `json:"encryption,omitempty"`
+	Files            []File      `json:"files"`
+}
+
+// File is one bundled file. Size and SHA256 are of the plain content;
+// Secret files are stored encrypted.
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	Mode   uint32 `json:"mode"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Encryption records how secret files are encrypted
+type Encryption struct {
+	Cipher string `json:"cipher"`
+	KDF    string `json:"kdf"`
+	Salt   []byte `json:"salt"`
+	N      int    `json:"n"`
+	R      int    `json:"r"`
+	P      int    `json:"p"`
+}
+
+// Options selects what Export includes
+type Options struct {
+	Home string
+	// IncludeKeys adds priv_validator_key.json, priv_validator_state.json and
+	// keyring directories, encrypted with Passphrase
+	IncludeKeys bool
+	Passphrase  []byte
+	// ScallFiles are scall.toml layers bundled under scall/
+	ScallFiles []string
+}
+
+// Bundle paths: files from the home keep their relative path under home/
+const (
+	homePrefix  = "home/"
+	scallPrefix = "scall/"
+)
+
+// secretFiles are the signing material bundled only with IncludeKeys
+var secretFiles = []string{
+	"config/priv_validator_key.json",
+	"data/priv_validator_state.json",
+}
+
+// publicFiles returns the home files bundled in the clear: config files,
+// genesis, node identity and the recorded desired state
+func publicFiles() []string {
+	var public []string
+	for _, prefix := range config.Prefixes() {
+		public = append(public, config.ConfigFiles[prefix])
+	}
+	return append(public, "config/genesis.json", "config/node_key.json",
+		filepath.Join(node.StateDirName, config.DesiredFile))
+}
+
+// isSecret reports whether a home-relative path is signing material: a
+// secretFiles entry or a file in a keyring directory
+func isSecret(rel string) bool {
+	for _, s := range secretFiles {
+		if rel == s {
+			return true
+		}
+	}
+	dir, _, nested := strings.Cut(rel, "/")
+	return nested && strings.HasPrefix(dir, "keyring-")
+}
+
+// knownPath reports whether Export writes bundle path p, so a crafted
+// manifest cannot place other files into the home
+func knownPath(p string) bool {
+	if name, ok := strings.CutPrefix(p, scallPrefix); ok {
+		return name != "" && !strings.Contains(name, "/")
+	}
+	rel, ok := strings.CutPrefix(p, homePrefix)
+	if !ok {
+		return false
+	}
+	if isSecret(rel) {
+		return true
+	}
+	for _, public := range publicFiles() {
+		if rel == filepath.ToSlash(public) {
+			return true
+		}
+	}
+	return false
+}
+
+type entry struct {
+	file File
+	data []byte
+}
+
+// Export writes a bundle of the node configuration to out
+func Export(out string, opts Options) (*Manifest, error) {
+	if opts.IncludeKeys && len(opts.Passphrase) == 0 {
+		return nil, fmt.Errorf("a passphrase is required to bundle keys")
+	}
+
+	m := &Manifest{Version: FormatVersion, CreatedAt: time.Now().UTC()}
+	m.Hostname, _ = os.Hostname()
+	m.ChainID = chainID(opts.Home)
+	m.NodeID, _ = node.ID(opts.Home)
+
+	var entries []entry
+	add := func(name, src string, secret bool) error {
+		info, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		entries = append(entries, entry{
+			file: File{Path: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:]), Mode: uint32(info.Mode().Perm()), Secret: secret},
+			data: data,
+		})
+		return nil
+	}
+	addHome := func(rel string, secret bool) error {
+		src := filepath.Join(opts.Home, rel)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			return nil
+		}
+		return add(homePrefix+filepath.ToSlash(rel), src, secret)
+	}
+
+	// Config files, genesis, node identity and the recorded desired state
+	for _, rel := range publicFiles() {
+		if err := addHome(rel, false); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, f := range opts.ScallFiles {
+		if err := add(fmt.Sprintf("%s%d-%s", scallPrefix, i, filepath.Base(f)), f, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.IncludeKeys {
+		m.ValidatorAddress = ValidatorAddress(opts.Home)
+		secrets := append([]string{}, secretFiles...)
+		keyrings, _ := filepath.Glob(filepath.Join(opts.Home, "keyring-*"))
+		for _, dir := range keyrings {
+			err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+				if err != nil || !info.Mode().IsRegular() {
+					return err
+				}
+				rel, err := filepath.Rel(opts.Home, p)
+				secrets = append(secrets, rel)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, rel := range secrets {
+			if err := addHome(rel, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Encrypt secrets with one key derived from the passphrase
+	var aead cipher.AEAD
+	if opts.IncludeKeys {
+		enc := &Encryption{Cipher: "aes-256-gcm", KDF: "scrypt", Salt: make([]byte, 16), N: scryptN, R: scryptR, P: scryptP}
+		if _, err := rand.Read(enc.Salt); err != nil {
+			return nil, err
+		}
+		var err error
+		if aead, err = newAEAD(enc, opts.Passphrase); err != nil {
+			return nil, err
+		}
+		m.Encryption = enc
+	}
+	for i := range entries {
+		m.Files = append(m.Files, entries[i].file)
+		if entries[i].file.Secret {
+			nonce := make([]byte, aead.NonceSize())
+			if _, err := rand.Read(nonce); err != nil {
+				return nil, err
+			}
+			entries[i].data = aead.Seal(nonce, nonce, entries[i].data, []byte(entries[i].file.Path))
+		}
+	}
+
+	if err := writeTar(out, m, entries); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func writeTar(out string, m *Manifest, entries []entry) error {
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	write := func(name string, mode int64, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: m.CreatedAt, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := write(ManifestName, 0644, append(manifest, '\n')); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := write(e.file.Path, 0600, e.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	// The bundle may hold encrypted keys and always holds node_key.json
+	return config.WriteFileAtomic(out, buf.Bytes(), 0600)
+}
+
+// Read opens a bundle and checks every file against the manifest. Secret
+// files are decrypted with passphrase; without one they are left out and
+// reported in the returned list.
+func Read(in string, passphrase []byte) (*Manifest, map[string][]byte, []string, error) {
+	f, err := os.Open(in)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("not a bundle: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var m *Manifest
+	raw := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if m == nil {
+			if hdr.Name != ManifestName {
+				return nil, nil, nil, fmt.Errorf("bundle does not start with %s", ManifestName)
+			}
+			m = &Manifest{}
+			if err := json.Unmarshal(data, m); err != nil {
+				return nil, nil, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			continue
+		}
+		raw[hdr.Name] = data
+	}
+	if m == nil {
+		return nil, nil, nil, fmt.Errorf("empty bundle")
+	}
+	if m.Version != FormatVersion {
+		return nil, nil, nil, fmt.Errorf("unsupported bundle version %d", m.Version)
+	}
+
+	var aead cipher.AEAD
+	if m.Encryption != nil && len(passphrase) > 0 {
+		if aead, err = newAEAD(m.Encryption, passphrase); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	files := make(map[string][]byte)
+	var skipped []string
+	for _, file := range m.Files {
+		if !safePath(file.Path) {
+			return nil, nil, nil, fmt.Errorf("unsafe path in bundle: %s", file.Path)
+		}
+		if !knownPath(file.Path) {
+			return nil, nil, nil, fmt.Errorf("unexpected file in bundle: %s", file.Path)
+		}
+		// The manifest is not authenticated; signing material must be
+		// encrypted whatever it claims
+		secret := strings.HasPrefix(file.Path, homePrefix) && isSecret(strings.TrimPrefix(file.Path, homePrefix))
+		if secret && !file.Secret {
+			return nil, nil, nil, fmt.Errorf("%s is signing material but not encrypted", file.Path)
+		}
+		if !secret && file.Secret {
+			return nil, nil, nil, fmt.Errorf("%s is marked secret but is not signing material", file.Path)
+		}
+		data, ok := raw[file.Path]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("%s is listed in the manifest but missing", file.Path)
+		}
+		delete(raw, file.Path)
+
+		if file.Secret {
+			if aead == nil {
+				skipped = append(skipped, file.Path)
+				continue
+			}
+			if len(data) < aead.NonceSize() {
+				return nil, nil, nil, fmt.Errorf("%s: truncated", file.Path)
+			}
+			nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+			if data, err = aead.Open(nil, nonce, sealed, []byte(file.Path)); err != nil {
+				return nil, nil, nil, fmt.Errorf("failed to decrypt %s: wrong passphrase or corrupted bundle", file.Path)
+			}
+		}
+
+		sum := sha256.Sum256(data)
+		if int64(len(data)) != file.Size || hex.EncodeToString(sum[:]) != file.SHA256 {
+			return nil, nil, nil, fmt.Errorf("%s does not match its checksum", file.Path)
+		}
+		files[file.Path] = data
+	}
+	if len(raw) > 0 {
+		var extra []string
+		for name := range raw {
+			extra = append(extra, name)
+		}
+		sort.Strings(extra)
+		return nil, nil, nil, fmt.Errorf("files not listed in the manifest: %s", strings.Join(extra, ", "))
+	}
+	return m, files, skipped, nil
+}
+
+// Restore writes the files read from a bundle into home. scall layers go to
+// <home>/.scaller/scall/.
+func Restore(home string, m *Manifest, files map[string][]byte) ([]string, error) {
+	var restored []string
+	for _, file := range m.Files {
+		data, ok := files[file.Path]
+		if !ok {
+			continue
+		}
+		if !knownPath(file.Path) {
+			return restored, fmt.Errorf("unexpected bundle path %s", file.Path)
+		}
+		var dest string
+		switch {
+		case strings.HasPrefix(file.Path, homePrefix):
+			dest = filepath.Join(home, filepath.FromSlash(strings.TrimPrefix(file.Path, homePrefix)))
+		case strings.HasPrefix(file.Path, scallPrefix):
+			dest = filepath.Join(node.StateDir(home), "scall", strings.TrimPrefix(file.Path, scallPrefix))
+		default:
+			return restored, fmt.Errorf("unexpected bundle path %s", file.Path)
+		}
+
+		// Keep the owner's access and never grant write or execute to others
+		dirMode, mode := os.FileMode(0755), os.FileMode(file.Mode)&0644|0600
+		if file.Secret {
+			dirMode, mode = 0700, 0600
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), dirMode); err != nil {
+			return restored, err
+		}
+		if err := config.WriteFileAtomic(dest, data, mode); err != nil {
+			return restored, fmt.Errorf("failed to write %s: %w", dest, err)
+		}
+		restored = append(restored, dest)
+	}
+	return restored, nil
+}
+
+// HasSecrets reports whether the bundle carries signing material
+func (m *Manifest) HasSecrets() bool {
+	for _, f := range m.Files {
+		if f.Secret {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatorAddress returns the consensus address from priv_validator_key.json
+func ValidatorAddress(home string) string {
+	data, err := os.ReadFile(filepath.Join(home, "config", "priv_validator_key.json"))
+	if err != nil {
+		return ""
+	}
+	var key struct {
+		Address string `json:"address"`
+	}
+	json.Unmarshal(data, &key)
+	return strings.ToUpper(key.Address)
+}
+
+func chainID(home string) string {
+	data, err := os.ReadFile(filepath.Join(home, "config", "genesis.json"))
+	if err != nil {
+		return ""
+	}
+	var g struct {
+		ChainID string `json:"chain_id"`
+	}
+	json.Unmarshal(data, &g)
+	return g.ChainID
+}
+
+func newAEAD(enc *Encryption, passphrase []byte) (cipher.AEAD, error) {
+	if enc.Cipher != "aes-256-gcm" || enc.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported encryption %s/%s", enc.KDF, enc.Cipher)
+	}
+	if enc.N > maxScryptN || enc.R > maxScryptR || enc.P > maxScryptP {
+		return nil, fmt.Errorf("scrypt parameters N=%d r=%d p=%d exceed N=%d r=%d p=%d",
+			enc.N, enc.R, enc.P, maxScryptN, maxScryptR, maxScryptP)
+	}
+	key, err := scrypt.Key(passphrase, enc.Salt, enc.N, enc.R, enc.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// safePath rejects absolute paths and paths escaping the bundle root
+func safePath(p string) bool {
+	clean := path.Clean(p)
+	return clean == p && !path.IsAbs(p) && clean != ".." && !strings.HasPrefix(clean, "../") &&
+		(strings.HasPrefix(p, homePrefix) || strings.HasPrefix(p, scallPrefix))
+}
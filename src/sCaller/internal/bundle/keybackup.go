@@ -0,0 +1,87 @@
+package bundle
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Kinds of key backups
+const (
+	KindConsensusKey = "consensus-key"
+	KindNodeKey      = "node-key"
+)
+
+// KeyBackup is a single key file encrypted with a passphrase, written by
+// 'scaller consensus-key backup' and 'scaller node-key backup'. The signing
+// state is not secret and kept in the clear, so a restore can check it
+// without the passphrase.
+type KeyBackup struct {
+	Version    int             `json:"version"`
+	Kind       string          `json:"kind"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Hostname   string          `json:"hostname"`
+	ID         string          `json:"id"`
+	SignState  json.RawMessage `json:"sign_state,omitempty"`
+	Encryption *Encryption     `json:"encryption"`
+	Data       []byte          `json:"data"`
+}
+
+// NewKeyBackup encrypts key, identified by id (consensus address or node ID)
+func NewKeyBackup(kind, id string, key, passphrase []byte) (*KeyBackup, error) {
+	enc := &Encryption{Cipher: "aes-256-gcm", KDF: "scrypt", Salt: make([]byte, 16), N: scryptN, R: scryptR, P: scryptP}
+	if _, err := rand.Read(enc.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(enc, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	return &KeyBackup{
+		Version:    FormatVersion,
+		Kind:       kind,
+		CreatedAt:  time.Now().UTC(),
+		Hostname:   hostname,
+		ID:         id,
+		Encryption: enc,
+		// Kind and ID are authenticated, so they cannot be swapped
+		Data: aead.Seal(nonce, nonce, key, []byte(kind+"/"+id)),
+	}, nil
+}
+
+// ReadKeyBackup parses a key backup, returning nil if data is not one
+func ReadKeyBackup(data []byte) *KeyBackup {
+	var b KeyBackup
+	if json.Unmarshal(data, &b) != nil || b.Kind == "" || b.Encryption == nil {
+		return nil
+	}
+	return &b
+}
+
+// Open decrypts the key file of b
+func (b *KeyBackup) Open(passphrase []byte) ([]byte, error) {
+	if b.Version > FormatVersion {
+		return nil, fmt.Errorf("backup version %d is newer than supported (%d)", b.Version, FormatVersion)
+	}
+	aead, err := newAEAD(b.Encryption, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.Data) < aead.NonceSize() {
+		return nil, errors.New("backup is truncated")
+	}
+	nonce, sealed := b.Data[:aead.NonceSize()], b.Data[aead.NonceSize():]
+	key, err := aead.Open(nil, nonce, sealed, []byte(b.Kind+"/"+b.ID))
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted backup")
+	}
+	return key, nil
+}
@@ -0,0 +1,161 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"scaller/internal/config"
+	"scaller/internal/node"
+)
+
+// exportedFile marks a home whose validator key was exported. While it
+// exists scaller start refuses to run, so the old and the new machine never
+// sign with the same key at the same time.
+const exportedFile = "key-exported.json"
+
+// KeyExport records where a validator key went
+type KeyExport struct {
+	At               time.Time `json:"at"`
+	Bundle           string    `json:"bundle"`
+	ValidatorAddress string    `json:"validator_address"`
+}
+
+// MarkExported records that the validator key of home was exported
+func MarkExported(home string, e KeyExport) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(node.StateDir(home), 0700); err != nil {
+		return err
+	}
+	return config.WriteFileAtomic(filepath.Join(node.StateDir(home), exportedFile), data, 0600)
+}
+
+// Exported returns the export record of home, or nil if its key was not exported
+func Exported(home string) (*KeyExport, error) {
+	data, err := os.ReadFile(filepath.Join(node.StateDir(home), exportedFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e KeyExport
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ClearExported removes the export record of home
+func ClearExported(home string) error {
+	err := os.Remove(filepath.Join(node.StateDir(home), exportedFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// commitFetches bounds the concurrent /commit requests of RecentlySigned
+const commitFetches = 8
+
+// RecentlySigned reports the latest of the last blocks commits on rpc that
+// carry a signature from address, or 0 if there is none. Commits are
+// fetched concurrently, commitFetches at a time.
+func RecentlySigned(rpc, address string, blocks int64) (int64, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	base := strings.TrimRight(rpc, "/")
+	if !strings.HasPrefix(base, "http") {
+		base = "http://" + base
+	}
+
+	var status struct {
+		Result struct {
+			SyncInfo struct {
+				LatestBlockHeight string `json:"latest_block_height"`
+			} `json:"sync_info"`
+		} `json:"result"`
+	}
+	if err := getJSON(client, base+"/status", &status); err != nil {
+		return 0, err
+	}
+	latest, err := strconv.ParseInt(status.Result.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid latest height %q", status.Result.SyncInfo.LatestBlockHeight)
+	}
+
+	address = strings.ToUpper(address)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		signed   int64
+		firstErr error
+	)
+	sem := make(chan struct{}, commitFetches)
+	for h := latest; h > latest-blocks && h > 0; h-- {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(h int64) {
+			defer func() { <-sem; wg.Done() }()
+			ok, err := commitSignedBy(client, base, h, address)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if ok && h > signed {
+				signed = h
+			}
+		}(h)
+	}
+	wg.Wait()
+	if signed > 0 {
+		return signed, nil
+	}
+	return 0, firstErr
+}
+
+// commitSignedBy reports whether the commit of height h carries a signature
+// from address (upper case hex)
+func commitSignedBy(client *http.Client, base string, h int64, address string) (bool, error) {
+	var commit struct {
+		Result struct {
+			SignedHeader struct {
+				Commit struct {
+					Signatures []struct {
+						ValidatorAddress string `json:"validator_address"`
+					} `json:"signatures"`
+				} `json:"commit"`
+			} `json:"signed_header"`
+		} `json:"result"`
+	}
+	if err := getJSON(client, fmt.Sprintf("%s/commit?height=%d", base, h), &commit); err != nil {
+		return false, err
+	}
+	for _, sig := range commit.Result.SignedHeader.Commit.Signatures {
+		if strings.ToUpper(sig.ValidatorAddress) == address {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func getJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
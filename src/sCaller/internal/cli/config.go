@@ -0,0 +1,470 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scaller/internal/config"
+	"scaller/internal/profile"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and change node configuration",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		for prefix, file := range configRegister {
+			if err := config.RegisterFile(prefix, file); err != nil {
+				Fatal("%v", err)
+			}
+		}
+	},
+	Long: `Reads and writes config.toml, app.toml and client.toml under --home.
+
+Keys use the scall.toml form: config.<key> for config.toml, app.<key> for
+app.toml and client.<key> for client.toml, e.g. config.p2p.seeds. Other files
+under --home are mapped with --file prefix=path. Elements of [[array]] tables
+are addressed by index, e.g. app.chain[0].id.
+
+Examples:
+  scaller config get config.p2p.seeds
+  scaller config set app.pruning custom
+  scaller config unset config.p2p.persistent_peers
+  scaller config diff
+  scaller config show --effective --config scall.toml
+  scaller config sources --profile chaosnet --set config.p2p.pex=false
+  scaller config check
+  scaller config rollback`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a config value",
+	Args:  cobra.ExactArgs(1),
+	Run:   runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a config value",
+	Long: `Sets a config value in place. The value is parsed as a TOML literal
+(true, 100, ["a", "b"]) and otherwise taken as a string, then checked and
+coerced against the config schema. Templates such as {{ .Hostname }} or
+{{ env "NAME" }} are rendered before writing.`,
+	Args: cobra.ExactArgs(2),
+	Run:  runConfigSet,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a config value so sekaid uses its default",
+	Args:  cobra.ExactArgs(1),
+	Run:   runConfigUnset,
+}
+
+var configDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show values that differ from sekaid defaults",
+	Run:   runConfigDiff,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show configuration as flat key = value lines",
+	Long: `Shows the values from the config files. With --effective, sekaid defaults
+are merged below the files and the override layers on top, giving the values
+sekaid would run with after those overrides are applied. Templated values are
+shown as written; they are rendered when applied.`,
+	Run: runConfigShow,
+}
+
+var configSourcesCmd = &cobra.Command{
+	Use:   "sources",
+	Short: "Show which layer sets each effective value",
+	Long: `Merges the configuration layers and prints every effective value with the
+layer it comes from. Later layers win:
+
+  defaults       sekaid defaults
+  files          config.toml, app.toml and client.toml under --home
+  profile        scall overrides of --profile
+  config:<path>  --config files, in the order given
+  env            SCALL_* environment variables; "__" separates key segments,
+                 so SCALL_CONFIG__P2P__SEEDS sets config.p2p.seeds and
+                 SCALL_APP__PRUNING_KEEP_RECENT sets app.pruning-keep-recent
+  set            --set key=value flags
+
+scaller join applies the same profile, config, env and set layers on top of
+the values it derives from its own flags.`,
+	Run: runConfigSources,
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Detect config drift from the last applied overrides",
+	Long: `Every time scaller applies overrides (join, config set, start with
+--config-drift enforce) the applied values are recorded as the desired state in
+<home>/.scaller/desired.json. check compares the config files with that state
+and exits with status 1 if a key was changed or removed since.
+
+Values rendered from env or file templates are compared by hash and never
+printed.
+
+Examples:
+  scaller config check
+  scaller config check --json
+  scaller config check --enforce   # re-apply desired values
+  scaller config check --accept    # adopt the current values as desired`,
+	Run: runConfigCheck,
+}
+
+var configRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Restore config files from a backup",
+	Long: `Every scaller run that changes config files first saves the previous
+versions to <home>/config/.backups/<timestamp>/. rollback restores the
+latest set, or the one given with --to. The files being replaced are backed
+up as well, so a rollback can be undone.
+
+Examples:
+  scaller config rollback --list
+  scaller config rollback
+  scaller config rollback --to 20240101T120000Z`,
+	Run: runConfigRollback,
+}
+
+var (
+	configHome      string
+	configStrict    bool
+	configEffective bool
+	configFiles     []string
+	configSets      []string
+	configProfile   string
+	configOverrides bool
+	configRegister  map[string]string
+	configJSON      bool
+	configEnforce   bool
+	configAccept    bool
+	configRollTo    string
+	configRollList  bool
+)
+
+func init() {
+	configCmd.PersistentFlags().StringVar(&configHome, "home", "/sekai", "sekaid home directory")
+	configCmd.PersistentFlags().StringToStringVar(&configRegister, "file", nil, "Map a key prefix to a file under --home, e.g. sekai=config/sekai.toml (repeatable)")
+	configSetCmd.Flags().BoolVar(&configStrict, "strict", false, "Reject keys unknown to the schema")
+	configShowCmd.Flags().BoolVar(&configEffective, "effective", false, "Merge sekaid defaults, files and override layers")
+	for _, c := range []*cobra.Command{configShowCmd, configSourcesCmd} {
+		c.Flags().StringSliceVar(&configFiles, "config", nil, "scall.toml overrides (repeatable, later files win)")
+		c.Flags().StringArrayVar(&configSets, "set", nil, "Override key=value (repeatable)")
+		c.Flags().StringVar(&configProfile, "profile", "", "Network profile whose scall overrides to include")
+	}
+	configSourcesCmd.Flags().BoolVar(&configOverrides, "overrides-only", false, "Only show values not coming from sekaid defaults")
+
+	configCheckCmd.Flags().BoolVar(&configJSON, "json", false, "Print drift as JSON")
+	configCheckCmd.Flags().BoolVar(&configEnforce, "enforce", false, "Re-apply the desired value of drifted keys")
+	configCheckCmd.Flags().BoolVar(&configAccept, "accept", false, "Record the current value of drifted keys as desired")
+
+	configRollbackCmd.Flags().StringVar(&configRollTo, "to", "", "Backup timestamp to restore (default latest)")
+	configRollbackCmd.Flags().BoolVar(&configRollList, "list", false, "List available backups")
+
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configDiffCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configSourcesCmd)
+	configCmd.AddCommand(configCheckCmd)
+	configCmd.AddCommand(configRollbackCmd)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) {
+	value, ok, err := config.GetValue(configHome, args[0])
+	if err != nil {
+		Fatal("%v", err)
+	}
+	if !ok {
+		Fatal("%s is not set", args[0])
+	}
+	if s, isString := value.(string); isString {
+		fmt.Println(s)
+		return
+	}
+	fmt.Println(config.FormatValue(value))
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) {
+	key := args[0]
+	if _, _, err := config.SplitKey(configHome, key); err != nil {
+		Fatal("%v", err)
+	}
+
+	scall := config.ScallConfig{key: config.ParseValue(args[1])}
+	warnings, err := scall.Validate(configStrict)
+	for _, w := range warnings {
+		Log("Warning: %s", w)
+	}
+	if err != nil {
+		Fatal("%v", err)
+	}
+
+	if err := scall.Apply(configHome); err != nil {
+		Fatal("Failed to set %s: %v", key, err)
+	}
+	Log("Set %s = %s", key, config.FormatValue(scall[key]))
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) {
+	if err := config.UnsetValue(configHome, args[0]); err != nil {
+		Fatal("%v", err)
+	}
+	Log("Unset %s", args[0])
+}
+
+func runConfigDiff(cmd *cobra.Command, args []string) {
+	defaults, err := sekaidDefaults()
+	if err != nil {
+		Fatal("Failed to generate sekaid defaults: %v", err)
+	}
+	current, err := config.LoadHome(configHome)
+	if err != nil {
+		Fatal("%v", err)
+	}
+
+	// Keys missing from the files fall back to defaults, so only keys that
+	// are present and differ change sekaid's behaviour
+	changes := 0
+	for _, key := range sortedKeys(current) {
+		def, known := defaults[key]
+		switch {
+		case !known:
+			fmt.Printf("+ %s = %s\n", key, config.FormatValue(current[key]))
+		case config.FormatValue(def) != config.FormatValue(current[key]):
+			fmt.Printf("~ %s: %s -> %s\n", key, config.FormatValue(def), config.FormatValue(current[key]))
+		default:
+			continue
+		}
+		changes++
+	}
+	if changes == 0 {
+		Log("No differences from sekaid defaults")
+	}
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) {
+	if !configEffective {
+		if len(configFiles) > 0 || len(configSets) > 0 || configProfile != "" {
+			Fatal("--config, --set and --profile require --effective")
+		}
+		current, err := config.LoadHome(configHome)
+		if err != nil {
+			Fatal("%v", err)
+		}
+		for _, key := range sortedKeys(current) {
+			fmt.Printf("%s = %s\n", key, config.FormatValue(current[key]))
+		}
+		return
+	}
+
+	values := configLayers().Merge()
+	for _, key := range sortedKeys(values) {
+		fmt.Printf("%s = %s\n", key, config.FormatValue(values[key]))
+	}
+}
+
+func runConfigSources(cmd *cobra.Command, args []string) {
+	layers := configLayers()
+	values := layers.Merge()
+	sources := layers.Sources()
+
+	for _, key := range sortedKeys(values) {
+		if configOverrides && sources[key] == "defaults" {
+			continue
+		}
+		fmt.Printf("%-10s %s = %s\n", sources[key], key, config.FormatValue(values[key]))
+	}
+}
+
+func runConfigCheck(cmd *cobra.Command, args []string) {
+	if configEnforce && configAccept {
+		Fatal("--enforce and --accept are mutually exclusive")
+	}
+
+	desired, err := config.LoadDesired(configHome)
+	if err != nil {
+		Fatal("Failed to load desired config state: %v", err)
+	}
+	if len(desired.Values) == 0 {
+		Log("No desired config state recorded for %s", configHome)
+		return
+	}
+
+	drifts, err := desired.Check(configHome)
+	if err != nil {
+		Fatal("Failed to check config drift: %v", err)
+	}
+
+	if configJSON {
+		out, _ := json.MarshalIndent(drifts, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		printDrift(drifts)
+	}
+	if len(drifts) == 0 {
+		return
+	}
+
+	keys := make([]string, len(drifts))
+	for i, d := range drifts {
+		keys[i] = d.Key
+	}
+	switch {
+	case configEnforce:
+		if err := desired.Overrides(keys).Apply(configHome); err != nil {
+			Fatal("Failed to enforce desired config: %v", err)
+		}
+		Log("Re-applied %d drifted key(s)", len(keys))
+	case configAccept:
+		if err := desired.Accept(configHome, keys); err != nil {
+			Fatal("Failed to record desired config: %v", err)
+		}
+		Log("Accepted current value of %d key(s)", len(keys))
+	default:
+		os.Exit(1)
+	}
+}
+
+// printDrift prints one line per drifted key
+func printDrift(drifts []config.Drift) {
+	if len(drifts) == 0 {
+		Log("No config drift")
+		return
+	}
+	for _, d := range drifts {
+		if d.Missing {
+			fmt.Printf("- %s: %s -> (unset)\n", d.Key, d.Want)
+		} else {
+			fmt.Printf("~ %s: %s -> %s\n", d.Key, d.Want, d.Got)
+		}
+	}
+}
+
+func runConfigRollback(cmd *cobra.Command, args []string) {
+	configDir := filepath.Join(configHome, "config")
+
+	if configRollList {
+		backups, err := config.ListBackups(configDir)
+		if err != nil {
+			Fatal("Failed to list backups: %v", err)
+		}
+		for _, b := range backups {
+			fmt.Printf("%s  %s\n", b.Timestamp, strings.Join(b.Files, ", "))
+		}
+		return
+	}
+
+	b, err := config.Rollback(configDir, configRollTo)
+	if err != nil {
+		Fatal("Rollback failed: %v", err)
+	}
+	Log("Restored %s from backup %s", strings.Join(b.Files, ", "), b.Timestamp)
+}
+
+// configLayers builds the layers for show --effective and sources: sekaid
+// defaults, the files under --home, then the override layers
+func configLayers() config.Layers {
+	defaults, err := sekaidDefaults()
+	if err != nil {
+		Fatal("Failed to generate sekaid defaults: %v", err)
+	}
+	current, err := config.LoadHome(configHome)
+	if err != nil {
+		Fatal("%v", err)
+	}
+
+	var p *profile.Profile
+	if configProfile != "" {
+		if p, err = profile.Load(configProfile); err != nil {
+			Fatal("Failed to load profile: %v", err)
+		}
+	}
+	overrides, err := overrideLayers(p, configFiles, configSets)
+	if err != nil {
+		Fatal("%v", err)
+	}
+	mergeLayers(overrides, false)
+
+	layers := config.Layers{{Name: "defaults", Values: defaults}, {Name: "files", Values: current}}
+	return append(layers, overrides...)
+}
+
+// overrideLayers returns the override layers shared by join and config, in
+// precedence order: profile, --config files, SCALL_* environment, --set
+func overrideLayers(p *profile.Profile, files, sets []string) (config.Layers, error) {
+	var layers config.Layers
+	if p != nil {
+		layers.Add("profile", p.Scall)
+	}
+	for _, path := range files {
+		values, err := config.LoadScall(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		layers.Add("config:"+path, values)
+	}
+	env, warnings := config.EnvLayer()
+	for _, w := range warnings {
+		Log("Warning: env: %s", w)
+	}
+	layers.Add("env", env)
+	set, err := config.ParseSet(sets)
+	if err != nil {
+		return nil, err
+	}
+	layers.Add("set", set)
+	return layers, nil
+}
+
+// mergeLayers checks every layer against the schema, coercing values in
+// place, and merges them. Warnings are logged and errors are fatal.
+func mergeLayers(layers config.Layers, strict bool) config.ScallConfig {
+	for _, layer := range layers {
+		warnings, err := layer.Values.Validate(strict)
+		for _, w := range warnings {
+			Log("Warning: %s: %s", layer.Name, w)
+		}
+		if err != nil {
+			Fatal("%s: %v", layer.Name, err)
+		}
+	}
+	return layers.Merge()
+}
+
+// sekaidDefaults initialises a throwaway home to read sekaid's default config
+func sekaidDefaults() (config.ScallConfig, error) {
+	tmp, err := os.MkdirTemp("", "scaller-defaults-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	cmd := exec.Command(sekaidPath, "init", "defaults", "--home", tmp, "--chain-id", "defaults")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, string(output))
+	}
+	return config.LoadHome(tmp)
+}
+
+func sortedKeys(s config.ScallConfig) []string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
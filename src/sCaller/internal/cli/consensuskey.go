@@ -0,0 +1,438 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"scaller/internal/bundle"
+	"scaller/internal/config"
+	"scaller/internal/keys"
+	"scaller/internal/node"
+
+	"github.com/spf13/cobra"
+)
+
+var consensusKeyCmd = &cobra.Command{
+	Use:   "consensus-key",
+	Short: "Show, generate, import and back up the validator consensus key",
+	Long: `Manages config/priv_validator_key.json, the ed25519 key the node signs
+blocks with. Commands that replace the key refuse to run while sekaid is
+running, and keep the previous key next to it as
+priv_validator_key.json.<time>.bak.`,
+}
+
+var consensusKeyShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the consensus address, public key and signing state",
+	Args:  cobra.NoArgs,
+	Run:   runConsensusKeyShow,
+}
+
+var consensusKeyGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a new consensus key",
+	Long: `Writes a new random consensus key, or derives it from a mnemonic with
+--mnemonic (read like join reads it: --mnemonic-file, --mnemonic-fd, --shamir
+and --bip39-passphrase apply). An existing key is only replaced with --force.
+
+The mnemonic derivation is scaller's own (ed25519 seed = SHA256 of the BIP39
+seed): sekaid init --recover and other tools derive a different key from the
+same mnemonic, so keep using scaller to recover it.
+
+A validator whose on-chain consensus key differs from the new key stops
+signing until the key is rotated on chain or the old key restored.
+
+Examples:
+  scaller consensus-key generate
+  echo "word1 word2 ..." | scaller consensus-key generate --mnemonic --force`,
+	Args: cobra.NoArgs,
+	Run:  runConsensusKeyGenerate,
+}
+
+var consensusKeyImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a consensus key with double-sign protection",
+	Long: `Imports a consensus key from priv_validator_key.json, a TMKMS softsign
+key (base64), a raw 32 or 64 byte ed25519 key, or a backup written by
+'scaller consensus-key backup' (passphrase from --passphrase-file,
+$SCALLER_KEY_PASSPHRASE or stdin).
+
+A key that signed before must never sign at or below its last signed
+height again. The height it is known to have signed at is taken from
+--state (priv_validator_state.json of the previous signer or a TMKMS state
+file) and --signed-height. With --rpc the last --check-blocks commits are
+searched for the key's signature and the import is refused while it still
+signs. One of --state, --signed-height or --rpc is required unless
+--skip-signing-check is given.
+
+The signing state stored in a backup is as old as the backup, so it is only
+a floor: it never satisfies the requirement above. If
+data/priv_validator_state.json is behind the state of --state or the backup,
+that state is adopted; if it is still behind --signed-height, the import is
+refused. consensus.double_sign_check_height is set so sekaid repeats the
+check at startup.
+
+Examples:
+  scaller consensus-key import old/priv_validator_key.json --state old/priv_validator_state.json
+  scaller consensus-key import validator.key.backup --passphrase-file /run/secrets/key --rpc http://8.8.8.8:26657
+  scaller consensus-key import /etc/tmkms/secrets/kira.key --signed-height 123456 --force`,
+	Args: cobra.ExactArgs(1),
+	Run:  runConsensusKeyImport,
+}
+
+var consensusKeyBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Write an encrypted backup of the consensus key",
+	Long: `Encrypts priv_validator_key.json with a passphrase read from
+--passphrase-file, $SCALLER_KEY_PASSPHRASE or stdin (scrypt, AES-256-GCM).
+The current signing state is stored in the backup unencrypted. Importing
+the backup uses it as a floor, but still needs --rpc, --signed-height or
+--state, since the key may have signed further after the backup.
+
+Example:
+  scaller consensus-key backup --out validator.key.backup --passphrase-file /run/secrets/key`,
+	Args: cobra.NoArgs,
+	Run:  runConsensusKeyBackup,
+}
+
+var (
+	consensusKeyHome string
+	consensusKeyJSON bool
+
+	consensusKeyGenerateMnemonic bool
+	consensusKeyGenerateForce    bool
+	consensusKeyGenerateSource   mnemonicSource
+
+	consensusKeyImportPassFile     string
+	consensusKeyImportState        string
+	consensusKeyImportSignedHeight int64
+	consensusKeyImportRPC          string
+	consensusKeyImportCheckBlocks  int64
+	consensusKeyImportSkipCheck    bool
+	consensusKeyImportForce        bool
+
+	consensusKeyBackupOut      string
+	consensusKeyBackupPassFile string
+)
+
+func init() {
+	consensusKeyCmd.PersistentFlags().StringVar(&consensusKeyHome, "home", "/sekai", "sekaid home directory")
+
+	consensusKeyShowCmd.Flags().BoolVar(&consensusKeyJSON, "json", false, "Print JSON")
+
+	consensusKeyGenerateCmd.Flags().BoolVar(&consensusKeyGenerateMnemonic, "mnemonic", false, "Derive the key from a mnemonic read from stdin")
+	consensusKeyGenerateCmd.Flags().BoolVar(&consensusKeyGenerateForce, "force", false, "Replace an existing key")
+	consensusKeyGenerateSource.register(consensusKeyGenerateCmd)
+
+	consensusKeyImportCmd.Flags().StringVar(&consensusKeyImportPassFile, "passphrase-file", "", "File with the backup passphrase (default $"+keyPassphraseEnv+", then stdin)")
+	consensusKeyImportCmd.Flags().StringVar(&consensusKeyImportState, "state", "", "Signing state of the previous signer (priv_validator_state.json or TMKMS state)")
+	consensusKeyImportCmd.Flags().Int64Var(&consensusKeyImportSignedHeight, "signed-height", 0, "Height the key is known to have signed at")
+	consensusKeyImportCmd.Flags().StringVar(&consensusKeyImportRPC, "rpc", "", "Network RPC used to check that the key is no longer signing")
+	consensusKeyImportCmd.Flags().Int64Var(&consensusKeyImportCheckBlocks, "check-blocks", 20, "Number of recent blocks searched for the key's signature")
+	consensusKeyImportCmd.Flags().BoolVar(&consensusKeyImportSkipCheck, "skip-signing-check", false, "Import without any double-sign check")
+	consensusKeyImportCmd.Flags().BoolVar(&consensusKeyImportForce, "force", false, "Replace an existing, different key")
+
+	consensusKeyBackupCmd.Flags().StringVar(&consensusKeyBackupOut, "out", "", "Backup file to write")
+	consensusKeyBackupCmd.Flags().StringVar(&consensusKeyBackupPassFile, "passphrase-file", "", "File with the backup passphrase (default $"+keyPassphraseEnv+", then stdin)")
+	consensusKeyBackupCmd.MarkFlagRequired("out")
+
+	consensusKeyCmd.AddCommand(consensusKeyShowCmd, consensusKeyGenerateCmd, consensusKeyImportCmd, consensusKeyBackupCmd)
+}
+
+// consensusKeyOutput is the consensus key as printed by consensus-key show
+type consensusKeyOutput struct {
+	Address string `json:"address"`
+	Valcons string `json:"valcons"`
+	PubKey  string `json:"pubkey"`
+	Height  int64  `json:"height"`
+	Round   int64  `json:"round"`
+	Step    int64  `json:"step"`
+}
+
+func consensusKeyPath(home string) string {
+	return filepath.Join(home, "config", "priv_validator_key.json")
+}
+
+func runConsensusKeyShow(cmd *cobra.Command, args []string) {
+	ck, err := keys.LoadConsensusKey(consensusKeyPath(consensusKeyHome))
+	if err != nil {
+		Fatal("Failed to load consensus key: %v", err)
+	}
+	defer ck.Zero()
+	state, err := node.LoadSignState(node.SignStatePath(consensusKeyHome))
+	if err != nil {
+		Fatal("Failed to read signing state: %v", err)
+	}
+
+	out := consensusKeyOutput{
+		Address: ck.HexAddress(),
+		Valcons: ck.ValconsAddress(),
+		PubKey:  base64.StdEncoding.EncodeToString(ck.PubKey()),
+		Height:  state.Height,
+		Round:   state.Round,
+		Step:    state.Step,
+	}
+	if consensusKeyJSON {
+		printJSON(out)
+		return
+	}
+	fmt.Printf("address: %s\nvalcons: %s\npubkey: %s\nsigned: %s\n", out.Address, out.Valcons, out.PubKey, state)
+}
+
+func runConsensusKeyGenerate(cmd *cobra.Command, args []string) {
+	path := consensusKeyPath(consensusKeyHome)
+	requireStopped(consensusKeyHome, "replacing the consensus key")
+	if _, err := os.Stat(path); err == nil && !consensusKeyGenerateForce {
+		Fatal("%s already exists; use --force to replace it", path)
+	}
+
+	var ck *keys.ConsensusKey
+	var err error
+	if consensusKeyGenerateMnemonic || consensusKeyGenerateSource.given() {
+		line, passphrase, rerr := consensusKeyGenerateSource.read()
+		if rerr != nil {
+			Fatal("%v", rerr)
+		}
+		ck, err = keys.ConsensusKeyFromMnemonic(string(line), string(passphrase))
+		zeroBytes(line)
+		zeroBytes(passphrase)
+	} else {
+		ck, err = keys.GenerateConsensusKey()
+	}
+	if err != nil {
+		Fatal("Failed to generate consensus key: %v", err)
+	}
+	defer ck.Zero()
+
+	installKeyFile(path, ck.File())
+	fmt.Printf("address: %s\nvalcons: %s\n", ck.HexAddress(), ck.ValconsAddress())
+}
+
+func runConsensusKeyImport(cmd *cobra.Command, args []string) {
+	home := consensusKeyHome
+	path := consensusKeyPath(home)
+	requireStopped(home, "importing a consensus key")
+
+	ck, backup := readKeyInput(args[0], bundle.KindConsensusKey, consensusKeyImportPassFile)
+	defer ck.Zero()
+	address := ck.HexAddress()
+	Log("Importing consensus key %s (%s)", address, ck.ValconsAddress())
+
+	if current, err := keys.LoadConsensusKey(path); err == nil {
+		same := current.HexAddress() == address
+		current.Zero()
+		if !same && !consensusKeyImportForce {
+			Fatal("%s holds a different key; use --force to replace it", path)
+		}
+	} else if !os.IsNotExist(err) && !consensusKeyImportForce {
+		Fatal("Failed to load %s: %v; use --force to replace it", path, err)
+	}
+
+	// The highest state the key is known to have signed at. The state in a
+	// backup is unauthenticated and as old as the backup, so it only sets a
+	// floor and does not count as a source.
+	var known node.SignState
+	sources := 0
+	if backup != nil && len(backup.SignState) > 0 {
+		s, err := node.ParseSignState(backup.SignState)
+		if err != nil {
+			Fatal("Invalid signing state in backup: %v", err)
+		}
+		known = s
+	}
+	if consensusKeyImportState != "" {
+		data, err := os.ReadFile(consensusKeyImportState)
+		if err != nil {
+			Fatal("Failed to read --state: %v", err)
+		}
+		s, err := node.ParseSignState(data)
+		if err != nil {
+			Fatal("%s: %v", consensusKeyImportState, err)
+		}
+		if known.Less(s) {
+			known = s
+		}
+		sources++
+	}
+	if consensusKeyImportSignedHeight > 0 || consensusKeyImportRPC != "" {
+		sources++
+	}
+	if sources == 0 && !consensusKeyImportSkipCheck {
+		Fatal("Cannot tell where key %s last signed; pass --state, --signed-height or --rpc, or --skip-signing-check", address)
+	}
+	checkNotSigning(consensusKeyImportRPC, address, consensusKeyImportCheckBlocks, consensusKeyImportSkipCheck)
+
+	statePath := node.SignStatePath(home)
+	local, err := node.LoadSignState(statePath)
+	if err != nil {
+		Fatal("Failed to read %s: %v", statePath, err)
+	}
+	adopt := local.Less(known)
+	final := local
+	if adopt {
+		final = known
+	}
+	if !consensusKeyImportSkipCheck && final.Height < consensusKeyImportSignedHeight {
+		Fatal("%s is at height %d but key %s signed at height %d; pass --state with the previous signer's state",
+			statePath, final.Height, address, consensusKeyImportSignedHeight)
+	}
+
+	if adopt {
+		writeSignState(statePath, known)
+		Log("Raised signing state from %s to %s", local, known)
+	}
+	installKeyFile(path, ck.File())
+	enableDoubleSignCheck(home)
+	fmt.Printf("address: %s\nvalcons: %s\n", address, ck.ValconsAddress())
+}
+
+func runConsensusKeyBackup(cmd *cobra.Command, args []string) {
+	path := consensusKeyPath(consensusKeyHome)
+	ck, err := keys.LoadConsensusKey(path)
+	if err != nil {
+		Fatal("Failed to load consensus key: %v", err)
+	}
+	address := ck.HexAddress()
+	ck.Zero()
+
+	state, err := os.ReadFile(node.SignStatePath(consensusKeyHome))
+	if err != nil && !os.IsNotExist(err) {
+		Fatal("Failed to read signing state: %v", err)
+	}
+	if len(state) > 0 {
+		if _, err := node.ParseSignState(state); err != nil {
+			Fatal("%v", err)
+		}
+	}
+	writeKeyBackup(bundle.KindConsensusKey, address, path, state, consensusKeyBackupOut, consensusKeyBackupPassFile)
+}
+
+// requireStopped refuses to continue while sekaid runs on home
+func requireStopped(home, what string) {
+	proc, err := node.FindSekaid(home)
+	if err != nil {
+		Fatal("Failed to look up sekaid process: %v", err)
+	}
+	if proc != nil {
+		Fatal("sekaid is running (pid %d); stop it before %s", proc.PID, what)
+	}
+}
+
+// readKeyInput reads an ed25519 key file in any supported form. Backups are
+// decrypted and returned as well, and must be of kind.
+func readKeyInput(path, kind, passFile string) (*keys.ConsensusKey, *bundle.KeyBackup) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		Fatal("Failed to read key: %v", err)
+	}
+	defer zeroBytes(data)
+
+	backup := bundle.ReadKeyBackup(data)
+	if backup != nil {
+		if backup.Kind != kind {
+			Fatal("%s is a %s backup, not a %s backup", path, backup.Kind, kind)
+		}
+		passphrase, err := readPassphrase(passFile, keyPassphraseEnv, "backup passphrase", minPassphrase, false)
+		if err != nil {
+			Fatal("Failed to read backup passphrase: %v", err)
+		}
+		plain, err := backup.Open(passphrase)
+		zeroBytes(passphrase)
+		if err != nil {
+			Fatal("Failed to decrypt %s: %v", path, err)
+		}
+		defer zeroBytes(plain)
+		Log("Decrypted %s backup of %s from %s at %s", kind, backup.ID, backup.Hostname, backup.CreatedAt.Format(time.RFC3339))
+		data = plain
+	}
+
+	ck, err := keys.ParseConsensusKey(data)
+	if err != nil {
+		Fatal("Failed to parse %s: %v", path, err)
+	}
+	return ck, backup
+}
+
+// installKeyFile writes the key file v to path at 0600, moving a different
+// existing file aside first
+func installKeyFile(path string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		Fatal("Failed to encode %s: %v", path, err)
+	}
+	defer zeroBytes(data)
+
+	old, err := os.ReadFile(path)
+	if err == nil && !bytes.Equal(old, data) {
+		backup := fmt.Sprintf("%s.%s.bak", path, time.Now().UTC().Format("20060102T150405Z"))
+		if err := os.WriteFile(backup, old, 0600); err != nil {
+			Fatal("Failed to back up %s: %v", path, err)
+		}
+		Log("Saved previous key to %s", backup)
+	}
+	zeroBytes(old)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		Fatal("Failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := config.WriteFileAtomic(path, data, 0600); err != nil {
+		Fatal("Failed to write %s: %v", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		Fatal("Failed to restrict %s: %v", path, err)
+	}
+	Log("Wrote %s", path)
+}
+
+// writeSignState writes s as priv_validator_state.json. Without sign bytes
+// sekaid refuses to sign again at exactly s, which is what a moved key needs.
+func writeSignState(path string, s node.SignState) {
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"height": fmt.Sprint(s.Height),
+		"round":  s.Round,
+		"step":   s.Step,
+	}, "", "  ")
+	if err != nil {
+		Fatal("Failed to encode signing state: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		Fatal("Failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := config.WriteFileAtomic(path, data, 0600); err != nil {
+		Fatal("Failed to write %s: %v", path, err)
+	}
+}
+
+// writeKeyBackup encrypts the key file at path into out
+func writeKeyBackup(kind, id, path string, state []byte, out, passFile string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		Fatal("Failed to read %s: %v", path, err)
+	}
+	defer zeroBytes(data)
+
+	passphrase, err := readPassphrase(passFile, keyPassphraseEnv, "backup passphrase", minPassphrase, false)
+	if err != nil {
+		Fatal("Failed to read backup passphrase: %v", err)
+	}
+	backup, err := bundle.NewKeyBackup(kind, id, data, passphrase)
+	zeroBytes(passphrase)
+	if err != nil {
+		Fatal("Failed to encrypt %s: %v", path, err)
+	}
+	backup.SignState = state
+
+	encoded, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		Fatal("Failed to encode backup: %v", err)
+	}
+	if err := config.WriteFileAtomic(out, encoded, 0600); err != nil {
+		Fatal("Failed to write %s: %v", out, err)
+	}
+	Log("Wrote encrypted %s backup of %s to %s", kind, id, out)
+}
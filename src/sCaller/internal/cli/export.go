@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	"scaller/internal/bundle"
+	"scaller/internal/node"
+
+	"github.com/spf13/cobra"
+)
+
+// bundlePassphraseEnv holds the bundle passphrase when no file is given
+const bundlePassphraseEnv = "SCALLER_BUNDLE_PASSPHRASE"
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Bundle node configuration for migration to another machine",
+	Long: `Writes a gzip-compressed tarball with a manifest, the config files,
+genesis.json, node_key.json, the recorded desired config state and any scall
+layers given with --scall.
+
+With --include-keys, priv_validator_key.json, priv_validator_state.json and
+the keyring directories are added, encrypted with a passphrase read from
+--passphrase-file or $SCALLER_BUNDLE_PASSPHRASE. sekaid must be stopped so
+the signing state is final, and the home is marked as exported afterwards:
+'scaller start' then refuses to run it, so the key never signs on two
+machines at once.
+
+Examples:
+  scaller export --out node.tar.gz
+  scaller export --out node.tar.gz --include-keys --passphrase-file /run/secrets/bundle
+  scaller export --out node.tar.gz --scall scall.toml`,
+	Run: runExport,
+}
+
+var (
+	exportHome           string
+	exportOut            string
+	exportIncludeKeys    bool
+	exportPassphraseFile string
+	exportScall          []string
+)
+
+func init() {
+	exportCmd.Flags().StringVar(&exportHome, "home", "/sekai", "sekaid home directory")
+	exportCmd.Flags().StringVar(&exportOut, "out", "", "Bundle file to write (e.g. bundle.tar.gz)")
+	exportCmd.Flags().BoolVar(&exportIncludeKeys, "include-keys", false, "Include validator key, signing state and keyrings (encrypted)")
+	exportCmd.Flags().StringVar(&exportPassphraseFile, "passphrase-file", "", "File with the bundle passphrase (default $"+bundlePassphraseEnv+")")
+	exportCmd.Flags().StringSliceVar(&exportScall, "scall", nil, "scall.toml layers to include (repeatable)")
+	exportCmd.MarkFlagRequired("out")
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	opts := bundle.Options{Home: exportHome, ScallFiles: exportScall, IncludeKeys: exportIncludeKeys}
+
+	if exportIncludeKeys {
+		proc, err := node.FindSekaid(exportHome)
+		if err != nil {
+			Fatal("Failed to look up sekaid process: %v", err)
+		}
+		if proc != nil {
+			Fatal("sekaid is running (pid %d); stop it before exporting keys so the signing state is final", proc.PID)
+		}
+		opts.Passphrase = readBundlePassphrase(exportPassphraseFile)
+		if len(opts.Passphrase) == 0 {
+			Fatal("--include-keys needs a passphrase: use --passphrase-file or $%s", bundlePassphraseEnv)
+		}
+	}
+
+	m, err := bundle.Export(exportOut, opts)
+	if err != nil {
+		Fatal("Export failed: %v", err)
+	}
+	Log("Wrote %s (%d files, chain %s, node %s)", exportOut, len(m.Files), m.ChainID, m.NodeID)
+
+	if exportIncludeKeys {
+		err := bundle.MarkExported(exportHome, bundle.KeyExport{
+			At:               m.CreatedAt,
+			Bundle:           exportOut,
+			ValidatorAddress: m.ValidatorAddress,
+		})
+		if err != nil {
+			Fatal("Failed to mark %s as exported: %v", exportHome, err)
+		}
+		Log("Validator key %s exported; 'scaller start' is now blocked on this machine", m.ValidatorAddress)
+	}
+}
+
+// readBundlePassphrase reads the passphrase from path, or from the
+// environment if path is empty. A trailing newline is dropped.
+func readBundlePassphrase(path string) []byte {
+	if path == "" {
+		return []byte(os.Getenv(bundlePassphraseEnv))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		Fatal("Failed to read passphrase file: %v", err)
+	}
+	return bytes.TrimRight(data, "\r\n")
+}
+
+// checkKeyExported refuses to start a node whose validator key was exported
+func checkKeyExported(home string, allow bool) {
+	e, err := bundle.Exported(home)
+	if err != nil {
+		Fatal("Failed to read key export record: %v", err)
+	}
+	if e == nil {
+		return
+	}
+	if !allow {
+		Fatal("Validator key %s was exported to %s at %s; refusing to start to avoid double signing (use --allow-exported-key if the other machine is retired)",
+			e.ValidatorAddress, e.Bundle, e.At.Format(time.RFC3339))
+	}
+	Log("Warning: Starting with validator key %s that was exported at %s", e.ValidatorAddress, e.At.Format(time.RFC3339))
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"scaller/internal/bundle"
+	"scaller/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// importDoubleSignCheckHeight is set on imported validators so sekaid itself
+// refuses to sign if the key signed any of the last blocks elsewhere
+const importDoubleSignCheckHeight = 10
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Restore a bundle written by scaller export",
+	Long: `Verifies every file of the bundle against its manifest checksum and
+restores it into --home. scall layers are placed in <home>/.scaller/scall/.
+An existing home is refused unless --force is given.
+
+Bundles with keys need the passphrase (--passphrase-file or
+$SCALLER_BUNDLE_PASSPHRASE), or --skip-keys to restore everything else.
+Before restoring a validator key, the last --check-blocks commits on --rpc
+are searched for its signature; if it still signs somewhere the import is
+refused. consensus.double_sign_check_height is set so sekaid repeats the
+check at startup.
+
+Examples:
+  scaller import --in node.tar.gz --home /sekai
+  scaller import --in node.tar.gz --passphrase-file /run/secrets/bundle --rpc http://8.8.8.8:26657`,
+	Run: runImport,
+}
+
+var (
+	importHome             string
+	importIn               string
+	importPassphraseFile   string
+	importSkipKeys         bool
+	importForce            bool
+	importRPC              string
+	importCheckBlocks      int64
+	importSkipSigningCheck bool
+)
+
+func init() {
+	importCmd.Flags().StringVar(&importHome, "home", "/sekai", "sekaid home directory to restore into")
+	importCmd.Flags().StringVar(&importIn, "in", "", "Bundle file to import")
+	importCmd.Flags().StringVar(&importPassphraseFile, "passphrase-file", "", "File with the bundle passphrase (default $"+bundlePassphraseEnv+")")
+	importCmd.Flags().BoolVar(&importSkipKeys, "skip-keys", false, "Restore everything except keys and signing state")
+	importCmd.Flags().BoolVar(&importForce, "force", false, "Overwrite an existing home")
+	importCmd.Flags().StringVar(&importRPC, "rpc", "", "Network RPC used to check that the validator key is no longer signing")
+	importCmd.Flags().Int64Var(&importCheckBlocks, "check-blocks", 20, "Number of recent blocks searched for the validator's signature")
+	importCmd.Flags().BoolVar(&importSkipSigningCheck, "skip-signing-check", false, "Do not check recent blocks for the validator's signature")
+	importCmd.MarkFlagRequired("in")
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	if _, err := os.Stat(filepath.Join(importHome, "config", "config.toml")); err == nil && !importForce {
+		Fatal("%s already has a configuration; use --force to overwrite it", importHome)
+	}
+
+	var passphrase []byte
+	if !importSkipKeys {
+		passphrase = readBundlePassphrase(importPassphraseFile)
+	}
+
+	m, files, skipped, err := bundle.Read(importIn, passphrase)
+	if err != nil {
+		Fatal("Import failed: %v", err)
+	}
+	if len(skipped) > 0 && !importSkipKeys {
+		Fatal("Bundle contains keys; give the passphrase or use --skip-keys")
+	}
+	Log("Verified %d files (chain %s, node %s, exported from %s at %s)",
+		len(m.Files)-len(skipped), m.ChainID, m.NodeID, m.Hostname, m.CreatedAt.Format("2006-01-02 15:04:05"))
+
+	withKeys := m.HasSecrets() && len(skipped) == 0
+	if withKeys && m.ValidatorAddress != "" {
+		if importRPC == "" && !importSkipSigningCheck {
+			Fatal("Bundle contains validator key %s; pass --rpc to check it is no longer signing, or --skip-signing-check", m.ValidatorAddress)
+		}
+		checkNotSigning(importRPC, m.ValidatorAddress, importCheckBlocks, importSkipSigningCheck)
+	}
+
+	restored, err := bundle.Restore(importHome, m, files)
+	if err != nil {
+		Fatal("Restore failed: %v", err)
+	}
+	for _, path := range restored {
+		Log("Restored %s", path)
+	}
+	if len(skipped) > 0 {
+		Log("Skipped %s", strings.Join(skipped, ", "))
+	}
+
+	if withKeys {
+		// The bundle's own export record does not apply to this machine
+		if err := bundle.ClearExported(importHome); err != nil {
+			Log("Warning: %v", err)
+		}
+		enableDoubleSignCheck(importHome)
+	}
+	Log("Import complete. Run 'scaller config check' and 'scaller start' to bring the node up.")
+}
+
+// checkNotSigning refuses to continue while the validator still signs blocks
+// on rpc. An empty rpc skips the check.
+func checkNotSigning(rpc, address string, blocks int64, skip bool) {
+	if skip {
+		Log("Warning: Skipping check that validator %s stopped signing", address)
+		return
+	}
+	if rpc == "" {
+		return
+	}
+	height, err := bundle.RecentlySigned(rpc, address, blocks)
+	if err != nil {
+		Fatal("Failed to check recent signatures on %s: %v", rpc, err)
+	}
+	if height > 0 {
+		Fatal("Validator %s signed block %d within the last %d blocks; stop the old node before importing its key",
+			address, height, blocks)
+	}
+	Log("Validator %s has not signed in the last %d blocks", address, blocks)
+}
+
+// enableDoubleSignCheck sets consensus.double_sign_check_height unless the
+// home already has it, so sekaid checks recent blocks for its own signature
+// before signing with an imported key
+func enableDoubleSignCheck(home string) {
+	current, _, _ := config.GetValue(home, "config.consensus.double_sign_check_height")
+	if n, ok := current.(int64); ok && n > 0 {
+		return
+	}
+	scall := config.ScallConfig{"config.consensus.double_sign_check_height": int64(importDoubleSignCheckHeight)}
+	if err := scall.Apply(home); err != nil {
+		Fatal("Failed to enable double sign check: %v", err)
+	}
+	Log("Enabled consensus.double_sign_check_height = %d", importDoubleSignCheckHeight)
+}
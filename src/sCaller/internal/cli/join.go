@@ -1,11 +1,7 @@
 package cli
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -14,9 +10,14 @@ import (
 
 	"scaller/internal/config"
 	"scaller/internal/genesis"
+	"scaller/internal/keys"
+	"scaller/internal/node"
+	"scaller/internal/peers"
+	"scaller/internal/preset"
+	"scaller/internal/profile"
 	"scaller/internal/statesync"
+	"scaller/internal/topology"
 
-	"github.com/cosmos/go-bip39"
 	"github.com/spf13/cobra"
 )
 
@@ -25,10 +26,79 @@ var joinCmd = &cobra.Command{
 	Short: "Initialize node and join network",
 	Long: `Initializes sekaid, fetches genesis, configures node, and starts it.
 
-Mnemonic is read from stdin.
+The mnemonic is read from --mnemonic-file (e.g. a Docker secret),
+--mnemonic-fd, $SCALLER_MNEMONIC_FILE or stdin, where a terminal gets a
+hidden prompt. --bip39-passphrase adds a BIP39 passphrase (25th word) on the
+next stdin line, and --shamir rebuilds the mnemonic from SLIP-39 shares.
+'scaller keys split' creates these shares, whose master secret is "scaller:"
+followed by the mnemonic's BIP39 entropy; shares of other SLIP-39 wallets,
+whose master secret is a BIP32 seed, are refused.
+
+The validator key is stored in the --keyring-backend keyring. The file
+backend encrypts it with a passphrase read from --keyring-passphrase-file,
+$SCALLER_KEYRING_PASSPHRASE or the next stdin line. The test
+backend stores it unencrypted and is refused on chain IDs that are not
+testnets (testnet, devnet, localnet, chaosnet) unless --allow-test-keyring is
+given. With --validator-address, a mnemonic deriving another address is
+refused before anything is written.
+
+Progress is recorded in <home>/.scaller/join.json, so re-running join after
+a failure resumes where it stopped. An existing home is kept as is; existing
+keys and chain data are never overwritten unless --force is given.
+
+The RPC node's P2P endpoint is read from its /status and used as a seed,
+together with --seeds. --persistent-peers and up to --harvest-peers peers from
+the RPC node's /net_info become persistent peers. Endpoints that do not
+accept a TCP connection are dropped.
+
+A network profile (--profile) bundles chain-id, genesis checksum, RPC nodes,
+seeds, statesync, pruning and scall overrides. Flags given explicitly take
+precedence over the profile.
+
+--remote-signer removes the local consensus key and makes sekaid listen for
+TMKMS on --remote-signer-laddr: a private interface such as the WireGuard IP,
+or a unix:///path socket for a signer on the same host. An address reachable
+publicly, including 0.0.0.0 on a host with a public interface address, is
+refused unless --allow-public-signer is given; 'scaller start' checks it
+again before every start and needs the same flag.
+
+--dry-run prints the planned steps and config changes. It contacts no RPC
+node or peer and needs no initialised home: seeds, peers, statesync trust,
+the chain ID for the keyring check and the topology role of the node ID are
+listed as lookups the join will make, and their values are not shown.
+
+Config overrides are layered, later layers winning over earlier ones:
+  1. values derived from join flags (peers, statesync, role, pruning)
+  2. profile scall overrides
+  3. --config files, in the order given
+  4. SCALL_* environment variables (SCALL_CONFIG__P2P__SEEDS=... sets config.p2p.seeds)
+  5. --set key=value flags
+Use 'scaller config sources' to see which layer set each value.
+
+--role validator|sentry|seed|archive applies pex, seed mode, address book,
+pruning and indexer settings for that role. With --topology, peers are taken
+from the topology file: validators connect only to their sentries, sentries
+keep validators private and unconditional. Without --role the role is looked
+up in the topology by this node's ID.
+
+--preset validator-low-latency|rpc-heavy|archive|low-memory sets pruning,
+mempool, p2p rates, db backend, IAVL cache, snapshot interval and tx indexer
+as a group. A preset may tune the role's settings, but not change its pruning;
+an explicit --prune wins over both. --prune custom takes --prune-keep-recent
+and --prune-interval. Combinations sekaid would reject (e.g. archive pruning
+with statesync, custom pruning below the SDK minimums, switching db backend
+on existing data) are refused.
 
 Example:
-  echo "word1 word2 ..." | scaller join --rpc-node 8.8.8.8:26657 --statesync`,
+  echo "word1 word2 ..." | scaller join --rpc-node 8.8.8.8:26657 --statesync
+  echo "word1 word2 ..." | scaller join --rpc-node 8.8.8.8:26657 --role sentry --topology topology.toml
+  echo "word1 word2 ..." | scaller join --profile chaosnet --rpc-node 8.8.8.8:26657
+  echo "word1 word2 ..." | scaller join --profile ./network.toml
+  printf '%s\n%s\n' "$MNEMONIC" "$PASSPHRASE" | scaller join --rpc-node 8.8.8.8:26657 --keyring-backend file
+  scaller join --rpc-node 8.8.8.8:26657 --dry-run
+  scaller join --rpc-node 8.8.8.8:26657 --remote-signer --remote-signer-laddr tcp://10.200.0.1:26659
+  scaller join --rpc-node 8.8.8.8:26657 --preset rpc-heavy --prune custom --prune-keep-recent 50000 --prune-interval 50
+  scaller join --rpc-node 8.8.8.8:26657 --config base.toml --config local.toml --set app.minimum-gas-prices=0ukex`,
 	Run: runJoin,
 }
 
@@ -39,89 +109,569 @@ var (
 	joinChainID      string
 	joinStateSync    bool
 	joinPrune        string
-	joinConfigFile   string
+	joinConfigFiles  []string
+	joinSets         []string
 	joinAutoStart    bool
 	joinSnapshotInt  int64
 	joinRemoteSigner bool
+	joinSignerLaddr  string
+	joinAllowPublic  bool
+	joinForce        bool
+	joinDryRun       bool
+
+	joinSeeds           []string
+	joinPersistentPeers []string
+	joinHarvestPeers    int
+	joinPeerTimeout     time.Duration
+
+	joinProfileName string
+	joinProfile     *profile.Profile
+	joinRPCNodes    []string
+
+	joinRole          string
+	joinTopologyFile  string
+	joinTopology      *topology.Topology
+	joinPruneExplicit bool
+	joinStrict        bool
+
+	joinPreset          string
+	joinPruneKeepRecent uint64
+	joinPruneInterval   uint64
+
+	joinKeyringBackend    string
+	joinKeyringPassFile   string
+	joinAllowTestKeyring  bool
+	joinValidatorAddress  string
+	joinKeyringPassphrase []byte
+	joinMnemonic          mnemonicSource
+
+	// joinLookups are the network and node lookups --dry-run leaves to the
+	// join itself
+	joinLookups []string
 )
 
+// testnetMarkers identify chain IDs of test networks, e.g. testnet-1 or chaosnet-3
+var testnetMarkers = []string{"testnet", "devnet", "localnet", "chaosnet"}
+
 func init() {
-	joinCmd.Flags().StringVar(&joinRPCNode, "rpc-node", "", "RPC node address (required)")
+	joinCmd.Flags().StringVar(&joinRPCNode, "rpc-node", "", "RPC node address (required unless the profile lists rpc_nodes)")
 	joinCmd.Flags().StringVar(&joinHome, "home", "/sekai", "sekaid home directory")
 	joinCmd.Flags().StringVar(&joinMoniker, "moniker", "node", "Node moniker")
 	joinCmd.Flags().StringVar(&joinChainID, "chain-id", "", "Chain ID (auto-detect if empty)")
 	joinCmd.Flags().BoolVar(&joinStateSync, "statesync", false, "Enable state sync")
-	joinCmd.Flags().StringVar(&joinPrune, "prune", "default", "Pruning mode: default|nothing|everything|custom")
-	joinCmd.Flags().StringVar(&joinConfigFile, "config", "", "Path to scall.toml for additional overrides")
+	joinCmd.Flags().StringVar(&joinPrune, "prune", "default", "Pruning mode: "+strings.Join(preset.PruningModes, "|"))
+	joinCmd.Flags().Uint64Var(&joinPruneKeepRecent, "prune-keep-recent", 0, "Recent heights to keep with --prune custom (default 100)")
+	joinCmd.Flags().Uint64Var(&joinPruneInterval, "prune-interval", 0, "Heights between pruning runs with --prune custom (default 10)")
+	joinCmd.Flags().StringVar(&joinPreset, "preset", "", "Performance preset: "+strings.Join(preset.Names, "|"))
+	joinCmd.Flags().StringSliceVar(&joinConfigFiles, "config", nil, "Path to scall.toml for additional overrides (repeatable, later files win)")
+	joinCmd.Flags().StringArrayVar(&joinSets, "set", nil, "Config override key=value, e.g. config.p2p.seeds=... (repeatable)")
 	joinCmd.Flags().BoolVar(&joinAutoStart, "start", true, "Auto-start sekaid after join")
 	joinCmd.Flags().Int64Var(&joinSnapshotInt, "snapshot-interval", 1000, "Snapshot interval for statesync trust height calculation")
 	joinCmd.Flags().BoolVar(&joinRemoteSigner, "remote-signer", false, "Enable remote signer mode (TMKMS)")
+	joinCmd.Flags().StringVar(&joinSignerLaddr, "remote-signer-laddr", defaultSignerLaddr, "Where sekaid listens for the remote signer: tcp://<private or WireGuard IP>:port or unix:///path")
+	joinCmd.Flags().BoolVar(&joinAllowPublic, "allow-public-signer", false, "Allow a --remote-signer-laddr that listens on a public address")
+	joinCmd.Flags().BoolVar(&joinForce, "force", false, "Overwrite existing home, keys and genesis, discarding recorded progress")
+	joinCmd.Flags().BoolVar(&joinDryRun, "dry-run", false, "Print planned actions and config changes without applying them, contacting no RPC node or peer")
+	joinCmd.Flags().StringSliceVar(&joinSeeds, "seeds", nil, "Additional seeds (nodeID@host:port, comma-separated)")
+	joinCmd.Flags().StringSliceVar(&joinPersistentPeers, "persistent-peers", nil, "Persistent peers (nodeID@host:port, comma-separated)")
+	joinCmd.Flags().IntVar(&joinHarvestPeers, "harvest-peers", 5, "Number of reachable peers of the RPC node to add as persistent peers (0 = disabled)")
+	joinCmd.Flags().DurationVar(&joinPeerTimeout, "peer-timeout", 3*time.Second, "TCP dial timeout for peer reachability checks")
+	joinCmd.Flags().StringVar(&joinRole, "role", "", "Node role: "+strings.Join(topology.Roles, "|"))
+	joinCmd.Flags().StringVar(&joinTopologyFile, "topology", "", "Topology file listing validators, sentries and seeds")
+	joinCmd.Flags().BoolVar(&joinStrict, "strict", false, "Reject config overrides with keys unknown to the schema")
+	joinCmd.Flags().StringVar(&joinKeyringBackend, "keyring-backend", "test", "Keyring backend for the validator key: "+strings.Join(keyringBackends, "|"))
+	joinCmd.Flags().StringVar(&joinKeyringPassFile, "keyring-passphrase-file", "", "File with the file keyring passphrase (default $"+keyringPassphraseEnv+", then stdin)")
+	joinCmd.Flags().BoolVar(&joinAllowTestKeyring, "allow-test-keyring", false, "Allow the unencrypted test keyring on chain IDs that are not testnets")
+	joinCmd.Flags().StringVar(&joinValidatorAddress, "validator-address", "", "Refuse a mnemonic that does not derive this kira1 or kiravaloper1 address")
+	joinMnemonic.register(joinCmd)
+	joinCmd.Flags().StringVar(&joinProfileName, "profile", "", "Network profile file or built-in name ("+strings.Join(profile.Builtin(), ", ")+")")
+}
+
+// Join steps recorded in the join state file
+const (
+	joinStepInit    = "init"
+	joinStepKey     = "key"
+	joinStepSigner  = "signer"
+	joinStepGenesis = "genesis"
+	joinStepConfig  = "config"
+)
 
-	joinCmd.MarkFlagRequired("rpc-node")
+// joinAction is a planned join step and what will happen to it
+type joinAction struct {
+	Step   string
+	Action string
+	Skip   bool
+	Refuse string
 }
 
 func runJoin(cmd *cobra.Command, args []string) {
-	var mnemonic string
-	var err error
+	if joinProfileName != "" {
+		loadJoinProfile(cmd)
+	}
+	if joinRPCNode == "" {
+		Fatal("--rpc-node is required (or a profile with rpc_nodes)")
+	}
+	joinPruneExplicit = cmd.Flags().Changed("prune")
+	if joinPreset != "" {
+		if _, err := preset.Settings(joinPreset); err != nil {
+			Fatal("%v", err)
+		}
+	}
+	if _, err := preset.Pruning(joinPrune, joinPruneKeepRecent, joinPruneInterval); err != nil {
+		Fatal("%v", err)
+	}
+	if joinRole != "" {
+		if _, err := topology.Settings(joinRole); err != nil {
+			Fatal("%v", err)
+		}
+	}
+	if joinRemoteSigner {
+		checkSignerLaddr(joinSignerLaddr, joinAllowPublic)
+	} else if cmd.Flags().Changed("remote-signer-laddr") {
+		Fatal("--remote-signer-laddr needs --remote-signer")
+	}
+	switch joinKeyringBackend {
+	case "test", "file", "os":
+	default:
+		Fatal("Unknown keyring backend %q (use %s)", joinKeyringBackend, strings.Join(keyringBackends, "|"))
+	}
+	if joinTopologyFile != "" {
+		t, err := topology.Load(joinTopologyFile)
+		if err != nil {
+			Fatal("%v", err)
+		}
+		joinTopology = t
+	}
 
-	// 1. Read mnemonic from stdin (skip if remote signer mode)
-	if !joinRemoteSigner {
-		Log("Reading mnemonic from stdin...")
-		mnemonic, err = readMnemonicFromStdin()
+	// Two joins on one home would race on the keyring, config and join state
+	unlock := func() {}
+	if !joinDryRun {
+		u, err := node.LockJoin(joinHome)
 		if err != nil {
-			Fatal("Failed to read mnemonic: %v", err)
+			Fatal("%v", err)
 		}
+		unlock = u
+	}
 
-		// Validate mnemonic
-		if !bip39.IsMnemonicValid(mnemonic) {
-			Fatal("Invalid mnemonic")
+	state, err := node.LoadJoinState(joinHome)
+	if err != nil {
+		Fatal("Failed to load join state: %v", err)
+	}
+	if joinForce && !joinDryRun {
+		if err := state.Reset(); err != nil {
+			Fatal("Failed to reset join state: %v", err)
 		}
-		Log("Mnemonic validated")
-	} else {
+	}
+
+	// 1. Plan steps and refuse before touching anything
+	plan := planJoin(state)
+	if joinDryRun {
+		printJoinPlan(plan)
+	}
+	for _, a := range plan {
+		if a.Refuse != "" && !joinDryRun {
+			Fatal("Refusing %s step: %s (use --force to override)", a.Step, a.Refuse)
+		}
+	}
+	addsKey := !joinRemoteSigner && !planSkips(plan, joinStepKey)
+	if addsKey {
+		if err := checkKeyringBackend(); err != nil {
+			if !joinDryRun {
+				Fatal("%v", err)
+			}
+			Log("Warning: %v", err)
+		}
+	}
+
+	// 2. Show config overrides without applying them
+	if joinDryRun {
+		printJoinDiff(resolveJoinConfig())
+		printJoinLookups()
+		return
+	}
+
+	// Read the mnemonic (only if the key still needs adding)
+	var mnemonic, bip39Passphrase []byte
+	var address string
+	if addsKey {
+		mnemonic, bip39Passphrase, err = joinMnemonic.read()
+		if err != nil {
+			Fatal("%v", err)
+		}
+
+		// Validate mnemonic and derive its address (key derivation works on
+		// strings; that copy is left to the GC)
+		var key *keys.Key
+		if joinValidatorAddress != "" {
+			key, err = keys.CheckMnemonic(string(mnemonic), string(bip39Passphrase), "", joinValidatorAddress)
+		} else {
+			key, err = keys.FromMnemonic(string(mnemonic), string(bip39Passphrase), "")
+		}
+		if err != nil {
+			zeroBytes(mnemonic)
+			zeroBytes(bip39Passphrase)
+			Fatal("%v", err)
+		}
+		key.Zero()
+		address = key.AccAddress()
+		Log("Mnemonic validated: validator %s (%s)", key.ValoperAddress(), address)
+
+		if joinKeyringBackend == "file" {
+			joinKeyringPassphrase, err = readKeyringPassphrase(joinKeyringPassFile)
+			if err != nil {
+				Fatal("Failed to read keyring passphrase: %v", err)
+			}
+		}
+	} else if joinRemoteSigner {
 		Log("Remote signer mode enabled - skipping mnemonic input")
 	}
 
-	// 2. Initialize sekaid
-	Log("Initializing sekaid...")
-	if err := initSekaid(joinHome, joinChainID, joinMoniker); err != nil {
-		Fatal("Failed to initialize sekaid: %v", err)
+	for _, a := range plan {
+		if a.Skip {
+			Log("Skipping %s: %s", a.Step, a.Action)
+			continue
+		}
+
+		switch a.Step {
+		case joinStepInit:
+			Log("Initializing sekaid...")
+			if err := initSekaid(joinHome, joinChainID, joinMoniker, joinForce); err != nil {
+				Fatal("Failed to initialize sekaid: %v", err)
+			}
+
+		case joinStepKey:
+			Log("Adding validator key...")
+			kr := &keyring{home: joinHome, backend: joinKeyringBackend, passphrase: joinKeyringPassphrase}
+			if joinForce && validatorKeyExists(joinHome, joinKeyringBackend) {
+				if err := kr.delete(validatorKeyName); err != nil {
+					Fatal("Failed to remove existing validator key: %v", err)
+				}
+			}
+			err := kr.add(validatorKeyName, mnemonic, bip39Passphrase, address)
+			zeroBytes(mnemonic)
+			zeroBytes(bip39Passphrase)
+			kr.close()
+			if err != nil {
+				Fatal("Failed to add validator key: %v", err)
+			}
+			Log("Validator key added to %s keyring", joinKeyringBackend)
+
+		case joinStepSigner:
+			// Remove priv_validator_key.json created by init (key lives in TMKMS)
+			privKeyPath := filepath.Join(joinHome, "config", "priv_validator_key.json")
+			if err := os.Remove(privKeyPath); err != nil && !os.IsNotExist(err) {
+				Log("Warning: Failed to remove priv_validator_key.json: %v", err)
+			} else {
+				Log("Removed priv_validator_key.json (key managed by TMKMS)")
+			}
+
+		case joinStepGenesis:
+			Log("Fetching genesis from %s...", joinRPCNode)
+			genesisPath := filepath.Join(joinHome, "config", "genesis.json")
+			if err := genesis.Fetch(joinRPCNode, genesisPath); err != nil {
+				Fatal("Failed to fetch genesis: %v", err)
+			}
+			if joinProfile != nil && joinProfile.GenesisSHA256 != "" {
+				verifyGenesisChecksum(genesisPath, joinProfile.GenesisSHA256)
+			}
+			Log("Genesis saved to %s", genesisPath)
+
+		case joinStepConfig:
+			// Built after init so the node's own ID is known for --topology
+			scall, _ := resolveJoinConfig()
+			Log("Applying config overrides...")
+			if err := scall.Apply(joinHome); err != nil {
+				Fatal("Failed to apply config overrides: %v", err)
+			}
+		}
+
+		if err := state.MarkDone(a.Step); err != nil {
+			Fatal("Failed to record join progress: %v", err)
+		}
 	}
 
-	// 3. Add validator key from mnemonic (skip if remote signer mode)
-	if !joinRemoteSigner {
-		Log("Adding validator key...")
-		if err := addValidatorKey(joinHome, mnemonic); err != nil {
-			Fatal("Failed to add validator key: %v", err)
+	Log("Node configured successfully")
+	unlock()
+
+	// 3. Start if requested
+	if joinAutoStart {
+		Log("Starting sekaid...")
+		runStart(nil, nil)
+	} else {
+		Log("Join complete. Run 'scaller start' to start the node.")
+	}
+}
+
+// resolveJoinRole returns the node role and, with --topology, the peer links
+// for this node. Without --role the role is looked up by node ID in the topology.
+func resolveJoinRole() (string, topology.Links) {
+	if joinTopology == nil {
+		return joinRole, topology.Links{}
+	}
+
+	selfID, err := node.ID(joinHome)
+	if err != nil && joinDryRun {
+		// sekaid init creates the node ID during the join
+		if joinRole == "" {
+			deferLookup("look up this node's role in %s by the node ID sekaid init creates", joinTopologyFile)
+			return "", topology.Links{}
+		}
+		deferLookup("leave this node's own ID, created by sekaid init, out of the %s peers", joinTopologyFile)
+	} else if err != nil {
+		Log("Warning: Node ID unknown (%v), topology entries are not filtered", err)
+	}
+
+	role := joinRole
+	if role == "" {
+		r, ok := joinTopology.RoleOfID(selfID)
+		if !ok {
+			Fatal("Node %s is not listed in the topology; pass --role", selfID)
+		}
+		role = r
+		Log("Topology role for node %s: %s", selfID, role)
+	}
+	return role, joinTopology.LinksFor(role, selfID)
+}
+
+// loadJoinProfile loads --profile and fills in every join setting that was
+// not given explicitly on the command line
+func loadJoinProfile(cmd *cobra.Command) {
+	p, err := profile.Load(joinProfileName)
+	if err != nil {
+		Fatal("Failed to load profile: %v", err)
+	}
+	joinProfile = p
+	Log("Using network profile %s", p.Name)
+
+	flags := cmd.Flags()
+	if !flags.Changed("chain-id") && p.ChainID != "" {
+		joinChainID = p.ChainID
+	}
+	if !flags.Changed("seeds") {
+		joinSeeds = p.Seeds
+	}
+	if !flags.Changed("persistent-peers") {
+		joinPersistentPeers = p.PersistentPeers
+	}
+	if !flags.Changed("prune") && p.Pruning != "" {
+		joinPrune = p.Pruning
+	}
+	if !flags.Changed("preset") && p.Preset != "" {
+		joinPreset = p.Preset
+	}
+	if !flags.Changed("remote-signer") {
+		joinRemoteSigner = p.RemoteSigner
+	}
+	if !flags.Changed("remote-signer-laddr") && p.RemoteSignerLaddr != "" {
+		joinSignerLaddr = p.RemoteSignerLaddr
+	}
+	if !flags.Changed("statesync") {
+		joinStateSync = p.StateSync.Enable
+	}
+	if !flags.Changed("snapshot-interval") && p.StateSync.SnapshotInterval > 0 {
+		joinSnapshotInt = p.StateSync.SnapshotInterval
+	}
+
+	if flags.Changed("rpc-node") || len(p.RPCNodes) == 0 {
+		return
+	}
+	if joinDryRun {
+		joinRPCNodes, joinRPCNode = p.RPCNodes, p.RPCNodes[0]
+		deferLookup("use the first of the profile RPC nodes that responds for chain %s: %s", joinChainID, strings.Join(p.RPCNodes, ", "))
+		return
+	}
+	joinRPCNodes = profile.ResponsiveRPCNodes(p.RPCNodes, joinChainID)
+	if len(joinRPCNodes) == 0 {
+		Fatal("None of the profile RPC nodes responded for chain %s: %s", joinChainID, strings.Join(p.RPCNodes, ", "))
+	}
+	joinRPCNode = joinRPCNodes[0]
+	Log("Using RPC node %s (%d of %d responsive)", joinRPCNode, len(joinRPCNodes), len(p.RPCNodes))
+}
+
+// verifyGenesisChecksum removes the fetched genesis and aborts if it does
+// not match the profile's expected sha256
+func verifyGenesisChecksum(genesisPath, expected string) {
+	sum, err := genesis.Checksum(genesisPath)
+	if err != nil {
+		Fatal("Failed to hash genesis: %v", err)
+	}
+	if !strings.EqualFold(sum, expected) {
+		os.Remove(genesisPath)
+		Fatal("Genesis checksum mismatch: got %s, profile expects %s", sum, expected)
+	}
+	Log("Genesis checksum verified")
+}
+
+// planJoin decides for each step whether to run, skip or refuse it based on
+// recorded progress and what already exists under --home
+func planJoin(state *node.JoinState) []joinAction {
+	var plan []joinAction
+
+	// --force discards recorded progress
+	done := func(step string) bool {
+		return !joinForce && state.Done(step)
+	}
+
+	// init
+	switch {
+	case done(joinStepInit):
+		plan = append(plan, joinAction{Step: joinStepInit, Action: "already completed", Skip: true})
+	case homeInitialised(joinHome) && joinForce:
+		plan = append(plan, joinAction{Step: joinStepInit, Action: "re-initialise existing home (--overwrite)"})
+	case homeInitialised(joinHome):
+		plan = append(plan, joinAction{Step: joinStepInit, Action: "home already initialised, keeping existing node files", Skip: true})
+	default:
+		plan = append(plan, joinAction{Step: joinStepInit, Action: "initialise sekaid home"})
+	}
+
+	// key or remote signer
+	if joinRemoteSigner {
+		if done(joinStepSigner) {
+			plan = append(plan, joinAction{Step: joinStepSigner, Action: "already completed", Skip: true})
+		} else {
+			plan = append(plan, joinAction{Step: joinStepSigner, Action: "remove local priv_validator_key.json"})
 		}
 	} else {
-		// Remove priv_validator_key.json created by init (key lives in TMKMS)
-		privKeyPath := filepath.Join(joinHome, "config", "priv_validator_key.json")
-		if err := os.Remove(privKeyPath); err != nil && !os.IsNotExist(err) {
-			Log("Warning: Failed to remove priv_validator_key.json: %v", err)
+		a := joinAction{Step: joinStepKey, Action: fmt.Sprintf("add validator key from mnemonic to %s keyring", joinKeyringBackend)}
+		switch {
+		case done(joinStepKey):
+			a = joinAction{Step: joinStepKey, Action: "already completed", Skip: true}
+		case validatorKeyExists(joinHome, joinKeyringBackend) && joinForce:
+			a.Action = "replace existing validator key"
+		case validatorKeyExists(joinHome, joinKeyringBackend):
+			a.Refuse = "validator key already exists in keyring"
+		}
+		plan = append(plan, a)
+	}
+
+	// genesis
+	a := joinAction{Step: joinStepGenesis, Action: fmt.Sprintf("fetch genesis from %s", joinRPCNode)}
+	switch {
+	case done(joinStepGenesis):
+		a = joinAction{Step: joinStepGenesis, Action: "already completed", Skip: true}
+	case hasChainData(joinHome) && joinForce:
+		a.Action += " (existing data/ is kept)"
+	case hasChainData(joinHome):
+		a.Refuse = "data/ already contains chain data"
+	}
+	plan = append(plan, a)
+
+	// config is idempotent and always re-applied so flag changes take effect
+	plan = append(plan, joinAction{Step: joinStepConfig, Action: "apply config.toml and app.toml overrides"})
+
+	return plan
+}
+
+func planSkips(plan []joinAction, step string) bool {
+	for _, a := range plan {
+		if a.Step == step {
+			return a.Skip
+		}
+	}
+	return true
+}
+
+func printJoinPlan(plan []joinAction) {
+	fmt.Println("Planned actions:")
+	for _, a := range plan {
+		switch {
+		case a.Refuse != "":
+			fmt.Printf("  [X] %-8s refuse: %s (use --force)\n", a.Step, a.Refuse)
+		case a.Skip:
+			fmt.Printf("  [ ] %-8s skip: %s\n", a.Step, a.Action)
+		default:
+			fmt.Printf("  [+] %-8s %s\n", a.Step, a.Action)
+		}
+	}
+	if joinAutoStart {
+		fmt.Println("  [+] start    start sekaid")
+	}
+}
+
+func printJoinDiff(scall config.ScallConfig, sources map[string]string) {
+	changes, err := scall.Diff(joinHome)
+	if err != nil {
+		Fatal("Failed to compute config diff: %v", err)
+	}
+
+	fmt.Println("\nConfig changes:")
+	if len(changes) == 0 {
+		fmt.Println("  (none)")
+		return
+	}
+	for _, c := range changes {
+		if c.Secret {
+			c.Old, c.New = "(secret)", "(secret)"
+		}
+		if c.Exists {
+			fmt.Printf("  %s: %v -> %v  [%s]\n", c.Key, c.Old, c.New, sources[c.Key])
 		} else {
-			Log("Removed priv_validator_key.json (key managed by TMKMS)")
+			fmt.Printf("  %s: (unset) -> %v  [%s]\n", c.Key, c.New, sources[c.Key])
 		}
 	}
+}
+
+// resolveJoinConfig merges the join layers and checks the result: setting
+// combinations, and a db backend switch on a node that already has data
+func resolveJoinConfig() (config.ScallConfig, map[string]string) {
+	layers := buildJoinLayers()
+	scall := mergeLayers(layers, joinStrict)
 
-	// 4. Fetch genesis
-	Log("Fetching genesis from %s...", joinRPCNode)
-	genesisPath := filepath.Join(joinHome, "config", "genesis.json")
-	if err := genesis.Fetch(joinRPCNode, genesisPath); err != nil {
-		Fatal("Failed to fetch genesis: %v", err)
+	warnings, err := preset.Check(scall)
+	for _, w := range warnings {
+		Log("Warning: %s", w)
+	}
+	if err != nil {
+		Fatal("%v", err)
 	}
-	Log("Genesis saved to %s", genesisPath)
 
-	// 5. Build scall config with overrides
+	if backend, ok := scall["config.db_backend"]; ok && hasChainData(joinHome) {
+		current, set, _ := config.GetValue(joinHome, "config.db_backend")
+		if set && current != backend {
+			Fatal("Refusing to switch config.db_backend from %v to %v: %s has chain data in the old format",
+				current, backend, joinHome)
+		}
+	}
+	return scall, layers.Sources()
+}
+
+// buildJoinLayers assembles the config override layers: values derived from
+// join flags, then profile, --config files, environment and --set
+func buildJoinLayers() config.Layers {
 	scall := make(config.ScallConfig)
 
-	// Set RPC node as seed (fetches node ID and formats as nodeID@ip:p2pPort)
-	Log("Fetching seed node info...")
-	seedAddr := formatSeed(joinRPCNode)
-	Log("Using seed: %s", seedAddr)
-	scall.SetValue("config.p2p.seeds", seedAddr)
+	role, links := resolveJoinRole()
 
-	// 6. Configure statesync if enabled
-	if joinStateSync {
+	// A validator behind sentries only connects to the sentries from the topology
+	if role == topology.RoleValidator && joinTopology != nil {
+		Log("Validator role: using topology sentries only, skipping peer discovery")
+		scall.SetValue("config.p2p.seeds", "")
+	} else if joinDryRun {
+		unless := ""
+		if joinTopology != nil && role == "" {
+			unless = ", unless the topology makes this node a validator"
+		}
+		deferLookup("use the P2P address of %s from its /status as seed, check --seeds and --persistent-peers accept TCP and set config.p2p.seeds and persistent_peers%s", joinRPCNode, unless)
+		if joinHarvestPeers > 0 {
+			deferLookup("add up to %d reachable peers from the /net_info of %s to config.p2p.persistent_peers", joinHarvestPeers, joinRPCNode)
+		}
+	} else {
+		// Seeds and persistent peers (RPC node, explicit lists and harvested peers)
+		Log("Resolving seeds and peers...")
+		seeds, persistent := resolvePeers()
+		Log("Using seeds: %s", peers.Join(seeds))
+		scall.SetValue("config.p2p.seeds", peers.Join(seeds))
+		if len(persistent) > 0 {
+			Log("Using persistent peers: %s", peers.Join(persistent))
+			scall.SetValue("config.p2p.persistent_peers", peers.Join(persistent))
+		}
+	}
+
+	// Configure statesync if enabled
+	if joinStateSync && joinDryRun {
+		scall.SetValue("config.statesync.enable", true)
+		deferLookup("fetch the statesync trust height and hash from %s and set config.statesync.rpc_servers, trust_height and trust_hash", joinRPCNode)
+	} else if joinStateSync {
 		Log("Configuring statesync...")
 		ssConfig, err := statesync.FetchConfig(joinRPCNode, joinSnapshotInt)
 		if err != nil {
@@ -131,191 +681,264 @@ func runJoin(cmd *cobra.Command, args []string) {
 		scall.SetValue("config.statesync.rpc_servers", ssConfig.RPCServers)
 		scall.SetValue("config.statesync.trust_height", ssConfig.TrustHeight)
 		scall.SetValue("config.statesync.trust_hash", ssConfig.TrustHash)
+		// Prefer distinct servers for light client verification when the profile has them
+		if len(joinRPCNodes) > 1 {
+			servers := make([]string, 2)
+			for i, n := range joinRPCNodes[:2] {
+				if !strings.HasPrefix(n, "http") {
+					n = "http://" + n
+				}
+				servers[i] = n
+			}
+			scall.SetValue("config.statesync.rpc_servers", strings.Join(servers, ","))
+		}
 		Log("Statesync configured: height=%d hash=%s", ssConfig.TrustHeight, ssConfig.TrustHash)
 	}
 
-	// 7. Configure remote signer if enabled (TMKMS)
+	// Configure remote signer if enabled (TMKMS)
 	if joinRemoteSigner {
 		Log("Configuring remote signer mode...")
-		// Listen for remote signer connections on port 26659
-		scall.SetValue("config.priv_validator_laddr", "tcp://0.0.0.0:26659")
-		Log("Remote signer listening on tcp://0.0.0.0:26659")
+		scall.SetValue("config.priv_validator_laddr", joinSignerLaddr)
+		Log("Remote signer listening on %s", joinSignerLaddr)
+	} else {
+		// Later sekaid commands on this home find the validator key
+		scall.SetValue("client.keyring-backend", joinKeyringBackend)
 	}
 
-	// 8. Configure pruning
-	applyPruningConfig(scall, joinPrune)
-
-	// 9. Load additional overrides from scall.toml if provided
-	if joinConfigFile != "" {
-		Log("Loading config overrides from %s...", joinConfigFile)
-		fileConfig, err := config.LoadScall(joinConfigFile)
+	// Role settings and topology links
+	var roleSettings config.ScallConfig
+	if role != "" {
+		settings, err := topology.Settings(role)
 		if err != nil {
-			Fatal("Failed to load config file: %v", err)
+			Fatal("%v", err)
 		}
-		// Merge file config into scall
-		for k, v := range fileConfig {
+		roleSettings = settings
+		for k, v := range settings {
 			scall.SetValue(k, v)
 		}
+		links.Apply(scall)
+		Log("Applied %s role settings", role)
 	}
 
-	// 10. Apply config overrides
-	configTomlPath := filepath.Join(joinHome, "config", "config.toml")
-	appTomlPath := filepath.Join(joinHome, "config", "app.toml")
-
-	Log("Applying config overrides...")
-	if err := scall.ApplyToConfigToml(configTomlPath); err != nil {
-		Fatal("Failed to apply config.toml overrides: %v", err)
-	}
-	if err := scall.ApplyToAppToml(appTomlPath); err != nil {
-		Fatal("Failed to apply app.toml overrides: %v", err)
+	// Performance preset; it may tune the role's settings but not its pruning
+	if joinPreset != "" {
+		settings, err := preset.Settings(joinPreset)
+		if err != nil {
+			Fatal("%v", err)
+		}
+		for _, c := range preset.Conflicts(roleSettings, settings) {
+			if strings.HasPrefix(c, "app.pruning ") && !joinPruneExplicit {
+				Fatal("Preset %s conflicts with role %s on %s", joinPreset, role, c)
+			}
+			Log("Warning: Preset %s overrides role %s setting %s", joinPreset, role, c)
+		}
+		for k, v := range settings {
+			scall.SetValue(k, v)
+		}
+		Log("Applied %s preset", joinPreset)
 	}
 
-	Log("Node configured successfully")
-
-	// 11. Start if requested
-	if joinAutoStart {
-		Log("Starting sekaid...")
-		runStart(nil, nil)
-	} else {
-		Log("Join complete. Run 'scaller start' to start the node.")
+	// Configure pruning; an explicit --prune wins over role and preset
+	if _, set := scall["app.pruning"]; !set || joinPruneExplicit {
+		pruning, err := preset.Pruning(joinPrune, joinPruneKeepRecent, joinPruneInterval)
+		if err != nil {
+			Fatal("%v", err)
+		}
+		delete(scall, "app.pruning-keep-recent")
+		delete(scall, "app.pruning-interval")
+		for k, v := range pruning {
+			scall.SetValue(k, v)
+		}
 	}
-}
 
-func readMnemonicFromStdin() (string, error) {
-	reader := bufio.NewReader(os.Stdin)
-	line, err := reader.ReadString('\n')
+	var layers config.Layers
+	layers.Add("join", scall)
+	overrides, err := overrideLayers(joinProfile, joinConfigFiles, joinSets)
 	if err != nil {
-		return "", err
+		Fatal("%v", err)
 	}
-	return strings.TrimSpace(line), nil
+	return append(layers, overrides...)
 }
 
-func initSekaid(home, chainID, moniker string) error {
-	args := []string{"init", moniker, "--home", home}
-	if chainID != "" {
-		args = append(args, "--chain-id", chainID)
-	}
-	args = append(args, "--overwrite")
+// deferLookup records a lookup --dry-run does not make
+func deferLookup(format string, args ...interface{}) {
+	joinLookups = append(joinLookups, fmt.Sprintf(format, args...))
+}
 
-	cmd := exec.Command("/sekaid", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("%v: %s", err, string(output))
+// printJoinLookups lists the lookups left to the join, whose config values
+// are missing from the changes printed before
+func printJoinLookups() {
+	if len(joinLookups) == 0 {
+		return
+	}
+	fmt.Println("\nLookups made by the join (not by --dry-run):")
+	for _, l := range joinLookups {
+		fmt.Printf("  [?] %s\n", l)
 	}
-	return nil
 }
 
-func addValidatorKey(home, mnemonic string) error {
-	Log("Mnemonic length: %d words", len(strings.Fields(mnemonic)))
-
-	// Write mnemonic to temp file to avoid stdin pipe issues
-	tmpFile := "/tmp/mnemonic.tmp"
-	mnemonicData := []byte(mnemonic + "\n")
-	if err := os.WriteFile(tmpFile, mnemonicData, 0600); err != nil {
-		return fmt.Errorf("failed to write temp file: %v", err)
+// homeInitialised reports whether sekaid init already ran for home
+func homeInitialised(home string) bool {
+	for _, name := range []string{"config.toml", "node_key.json"} {
+		if _, err := os.Stat(filepath.Join(home, "config", name)); err != nil {
+			return false
+		}
 	}
-	defer secureDelete(tmpFile, len(mnemonicData))
-
-	// Use shell to pipe file content to sekaid
-	shellCmd := fmt.Sprintf("cat %s | /sekaid keys add validator --home %s --keyring-backend test --recover", tmpFile, home)
-	cmd := exec.Command("/bin/sh", "-c", shellCmd)
+	return true
+}
 
-	output, err := cmd.CombinedOutput()
+// hasChainData reports whether data/ holds anything besides the signing state
+func hasChainData(home string) bool {
+	entries, err := os.ReadDir(filepath.Join(home, "data"))
 	if err != nil {
-		return fmt.Errorf("%v: %s", err, string(output))
+		return false
 	}
-	Log("Key added: %s", string(output))
-	return nil
+	for _, e := range entries {
+		if e.Name() != "priv_validator_state.json" {
+			return true
+		}
+	}
+	return false
 }
 
-// secureDelete overwrites file with zeros before removing it
-func secureDelete(path string, size int) {
-	// Overwrite with zeros
-	zeros := make([]byte, size)
-	if f, err := os.OpenFile(path, os.O_WRONLY, 0600); err == nil {
-		f.Write(zeros)
-		f.Sync()
-		f.Close()
+// validatorKeyExists reports whether the keyring holds the validator key
+func validatorKeyExists(home, backend string) bool {
+	if backend == "os" {
+		// The OS keyring lives outside of home; ask sekaid
+		cmd := exec.Command("/sekaid", "keys", "show", validatorKeyName,
+			"--home", home, "--keyring-backend", backend)
+		return cmd.Run() == nil
 	}
-	os.Remove(path)
+	_, err := os.Stat(filepath.Join(home, "keyring-"+backend, validatorKeyName+".info"))
+	return err == nil
 }
 
-// statusNodeResponse represents the RPC /status response for node info
-type statusNodeResponse struct {
-	Result struct {
-		NodeInfo struct {
-			ID string `json:"id"`
-		} `json:"node_info"`
-	} `json:"result"`
+// checkKeyringBackend refuses the unencrypted test keyring on chains that are
+// not testnets, unless --allow-test-keyring is given
+func checkKeyringBackend() error {
+	if joinKeyringBackend != "test" || joinAllowTestKeyring {
+		return nil
+	}
+	chainID := joinChainID
+	if chainID == "" && joinDryRun {
+		deferLookup("read the chain ID from %s and refuse the test keyring unless it is a testnet", joinRPCNode)
+		return nil
+	}
+	if chainID == "" {
+		network, err := peers.Network(joinRPCNode)
+		if err != nil {
+			return fmt.Errorf("cannot determine chain ID to check the test keyring: %v", err)
+		}
+		chainID = network
+	}
+	if isTestnetChainID(chainID) {
+		return nil
+	}
+	return fmt.Errorf("chain %s is not a testnet and the test keyring stores the validator key unencrypted; use --keyring-backend file|os or --allow-test-keyring", chainID)
 }
 
-// fetchNodeID fetches the node ID from an RPC endpoint
-func fetchNodeID(rpcNode string) (string, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-
-	// Ensure rpcNode has scheme
-	url := rpcNode
-	if !strings.HasPrefix(url, "http") {
-		url = "http://" + url
+func isTestnetChainID(chainID string) bool {
+	chainID = strings.ToLower(chainID)
+	for _, marker := range testnetMarkers {
+		if strings.Contains(chainID, marker) {
+			return true
+		}
 	}
+	return false
+}
 
-	resp, err := client.Get(url + "/status")
-	if err != nil {
-		return "", err
+func initSekaid(home, chainID, moniker string, overwrite bool) error {
+	args := []string{"init", moniker, "--home", home}
+	if chainID != "" {
+		args = append(args, "--chain-id", chainID)
+	}
+	if overwrite {
+		args = append(args, "--overwrite")
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	cmd := exec.Command("/sekaid", args...)
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", err
+		return fmt.Errorf("%v: %s", err, string(output))
 	}
+	return nil
+}
 
-	var status statusNodeResponse
-	if err := json.Unmarshal(body, &status); err != nil {
-		return "", err
+// resolvePeers collects seeds and persistent peers from the RPC node, explicit
+// flags and the RPC node's /net_info, keeping only endpoints that accept TCP
+func resolvePeers() (seeds, persistent []peers.Peer) {
+	var seedCandidates, peerCandidates []peers.Peer
+	seen := make(map[string]bool)
+	add := func(list *[]peers.Peer, p peers.Peer) {
+		if seen[p.ID] {
+			return
+		}
+		seen[p.ID] = true
+		*list = append(*list, p)
 	}
 
-	if status.Result.NodeInfo.ID == "" {
-		return "", fmt.Errorf("empty node ID in response")
+	for _, s := range joinSeeds {
+		p, err := peers.Parse(s)
+		if err != nil {
+			Fatal("Invalid --seeds entry: %v", err)
+		}
+		add(&seedCandidates, p)
+	}
+	for _, s := range joinPersistentPeers {
+		p, err := peers.Parse(s)
+		if err != nil {
+			Fatal("Invalid --persistent-peers entry: %v", err)
+		}
+		add(&peerCandidates, p)
 	}
 
-	return status.Result.NodeInfo.ID, nil
-}
-
-// formatSeed formats the RPC node address as a proper seed address
-// Input: ip:rpcPort (e.g., 3.123.154.245:26657)
-// Output: nodeID@ip:p2pPort (e.g., abc123@3.123.154.245:26656)
-func formatSeed(rpcNode string) string {
-	// Fetch node ID
-	nodeID, err := fetchNodeID(rpcNode)
+	// The RPC node itself is used as a seed
+	rpcPeer, err := peers.FromStatus(joinRPCNode)
 	if err != nil {
-		Log("Warning: Failed to fetch node ID: %v, using address only", err)
-		return rpcNode
+		if len(seedCandidates)+len(peerCandidates) == 0 {
+			Fatal("Failed to derive P2P address of %s: %v", joinRPCNode, err)
+		}
+		Log("Warning: Failed to derive P2P address of %s: %v", joinRPCNode, err)
+	} else {
+		Log("RPC node P2P endpoint: %s", rpcPeer)
+		add(&seedCandidates, rpcPeer)
 	}
 
-	// Extract IP from rpcNode (remove port)
-	ip := rpcNode
-	if idx := strings.LastIndex(rpcNode, ":"); idx != -1 {
-		ip = rpcNode[:idx]
-	}
+	seeds = checkPeers(seedCandidates, "seed")
+	persistent = checkPeers(peerCandidates, "persistent peer")
 
-	// P2P port is typically 26656 (RPC is 26657)
-	p2pPort := "26656"
+	if joinHarvestPeers > 0 {
+		harvested, err := peers.Harvest(joinRPCNode)
+		if err != nil {
+			Log("Warning: Failed to harvest peers from %s: %v", joinRPCNode, err)
+		}
+		var candidates []peers.Peer
+		for _, p := range harvested {
+			if !seen[p.ID] {
+				candidates = append(candidates, p)
+			}
+		}
+		good, _ := peers.Reachable(candidates, joinPeerTimeout)
+		if len(good) > joinHarvestPeers {
+			good = good[:joinHarvestPeers]
+		}
+		for _, p := range good {
+			add(&persistent, p)
+		}
+		Log("Harvested %d reachable peers from %s", len(good), joinRPCNode)
+	}
 
-	return fmt.Sprintf("%s@%s:%s", nodeID, ip, p2pPort)
+	if len(seeds)+len(persistent) == 0 {
+		Fatal("No reachable seeds or peers")
+	}
+	return seeds, persistent
 }
 
-func applyPruningConfig(scall config.ScallConfig, mode string) {
-	switch mode {
-	case "nothing":
-		scall.SetValue("app.pruning", "nothing")
-	case "everything":
-		scall.SetValue("app.pruning", "everything")
-	case "custom":
-		scall.SetValue("app.pruning", "custom")
-		scall.SetValue("app.pruning-keep-recent", "100")
-		scall.SetValue("app.pruning-interval", "10")
-	default: // "default"
-		scall.SetValue("app.pruning", "default")
+// checkPeers dials each peer and drops unreachable ones with a warning
+func checkPeers(list []peers.Peer, kind string) []peers.Peer {
+	ok, failed := peers.Reachable(list, joinPeerTimeout)
+	for p, err := range failed {
+		Log("Warning: Dropping unreachable %s %s: %v", kind, p, err)
 	}
+	return ok
 }
@@ -1,46 +1,714 @@
 package cli
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"os/exec"
+	"os"
+
+	"scaller/internal/config"
+	"scaller/internal/keys"
+	"scaller/internal/pty"
 
 	"github.com/spf13/cobra"
 )
 
+// keyPassphraseEnv holds the passphrase of exported or imported armored keys
+const keyPassphraseEnv = "SCALLER_KEY_PASSPHRASE"
+
 var keysAddCmd = &cobra.Command{
 	Use:   "keys-add",
 	Short: "Add a new key to the keyring",
-	Long: `Adds a new key to the sekaid keyring.
+	Long: `Generates a BIP39 mnemonic, or reads one with --recover (from stdin, a
+hidden prompt on a terminal, --mnemonic-file, --mnemonic-fd or SLIP-39
+shares with --shamir), derives the account key (HD path m/44'/118'/0'/0/0)
+and stores it in the sekaid keyring. The kira1 and kiravaloper1 addresses
+are printed, followed by the mnemonic of a generated key. --bip39-passphrase
+adds a BIP39 passphrase (25th word), read after the mnemonic; the key sekaid
+stores is checked to have the address derived here.
 
-Example:
-  scaller keys-add --name genesis`,
+--words sets the mnemonic length. --extra-entropy mixes additional randomness
+(e.g. dice rolls) into the system random source. --entropy-file and
+--entropy-fd use exactly the hex entropy read from a file ("-" for stdin, a
+hidden prompt on a terminal) or a file descriptor, for keys generated
+offline; the entropy is the whole key, so it is never taken from the command
+line.
+
+The file backend reads its passphrase from --keyring-passphrase-file,
+$SCALLER_KEYRING_PASSPHRASE or the next stdin line.
+
+Examples:
+  scaller keys-add --name genesis
+  scaller keys-add --name genesis --words 12 --extra-entropy "$(cat dice.txt)"
+  scaller keys-add --name validator --entropy-file /run/secrets/entropy
+  echo "word1 word2 ..." | scaller keys-add --name validator --recover --expected-address kira1...
+  scaller keys-add --name validator --recover --mnemonic-file /run/secrets/mnemonic --bip39-passphrase-file /run/secrets/bip39`,
 	Run: runKeysAdd,
 }
 
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "List, show, export, import, delete, split and migrate keyring keys",
+	Long: `Manages keys in the sekaid keyring of --home.
+
+The file backend reads its passphrase from --keyring-passphrase-file,
+$SCALLER_KEYRING_PASSPHRASE or stdin. Secrets read from stdin come one per
+line, in this order: mnemonic, BIP39 passphrase, keyring passphrase, armored
+key passphrase, destination keyring passphrase.`,
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List keys with their addresses",
+	Args:  cobra.NoArgs,
+	Run:   runKeysList,
+}
+
+var keysShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show the address, operator address and public key of a key",
+	Long: `Shows the kira1 account address, kiravaloper1 operator address and public
+key of a key. For the validator key (the one join stores as "validator"),
+the node's kiravalcons1 consensus address and consensus public key from
+config/priv_validator_key.json are shown as well.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runKeysShow,
+}
+
+var keysExportCmd = &cobra.Command{
+	Use:   "export <name>",
+	Short: "Export a key as an armored, passphrase-encrypted private key",
+	Long: `Writes the key as an ASCII-armored private key encrypted with a
+passphrase read from --passphrase-file, $SCALLER_KEY_PASSPHRASE or stdin.
+
+Example:
+  scaller keys export validator --out validator.armor --passphrase-file /run/secrets/key`,
+	Args: cobra.ExactArgs(1),
+	Run:  runKeysExport,
+}
+
+var keysImportCmd = &cobra.Command{
+	Use:   "import <name>",
+	Short: "Import a key from an armored private key or a mnemonic",
+	Long: `Imports an armored private key written by 'scaller keys export' or
+'sekaid keys export' (--armor, with its passphrase), or recovers the key from
+a mnemonic (--mnemonic, read from stdin or the sources of keys-add).
+
+Examples:
+  scaller keys import validator --armor validator.armor --passphrase-file /run/secrets/key
+  echo "word1 word2 ..." | scaller keys import validator --mnemonic --expected-address kira1...
+  scaller keys import validator --shamir --mnemonic-file /run/secrets/shares`,
+	Args: cobra.ExactArgs(1),
+	Run:  runKeysImport,
+}
+
+var keysDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a key after confirmation",
+	Args:  cobra.ExactArgs(1),
+	Run:   runKeysDelete,
+}
+
+var keysSplitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "Split a mnemonic into SLIP-39 Shamir shares",
+	Long: `Reads a BIP39 mnemonic like keys-add --recover does (stdin, a hidden
+prompt on a terminal, --mnemonic-file or --mnemonic-fd) and splits the master
+secret "` + keys.ShamirSecretPrefix + `" + its entropy into SLIP-39 shares, which
+join, keys-add and keys import recover with --shamir. Each --group is a
+member threshold and count ("2-of-3"); --group-threshold groups are needed.
+--bip39-passphrase or --bip39-passphrase-file encrypts the shares with a
+SLIP-39 passphrase, asked for again on recovery; a BIP39 passphrase of the key
+itself is not part of the shares. The shares are printed one per line, per
+group, after checking they recover the mnemonic.
+
+Examples:
+  scaller keys split --mnemonic-file /run/secrets/mnemonic --group 3-of-5
+  scaller keys split --group-threshold 2 --group 2-of-3 --group 1-of-1 --group 3-of-5`,
+	Args: cobra.NoArgs,
+	Run:  runKeysSplit,
+}
+
+var keysMigrateCmd = &cobra.Command{
+	Use:   "migrate [name...]",
+	Short: "Move keys to another keyring backend",
+	Long: `Copies keys (all keys if no names are given) from the --keyring-backend
+keyring to the --to keyring. Each key is exported with a one-time passphrase
+held in memory, imported and checked to have the same address in the
+destination. With --delete-source the key is then removed from the source.
+
+Example:
+  scaller keys migrate --keyring-backend test --to file --delete-source`,
+	Run: runKeysMigrate,
+}
+
 var (
-	keysAddHome    string
-	keysAddName    string
-	keysAddBackend string
+	keysAddHome         string
+	keysAddName         string
+	keysAddBackend      string
+	keysAddRecover      bool
+	keysAddWords        int
+	keysAddEntropyFile  string
+	keysAddEntropyFD    int
+	keysAddExtraEntropy string
+	keysAddExpected     string
+	keysAddPassFile     string
+	keysAddMnemonic     mnemonicSource
+
+	keysHome     string
+	keysBackend  string
+	keysPassFile string
+	keysJSON     bool
+
+	keysExportOut      string
+	keysExportPassFile string
+
+	keysImportArmor    string
+	keysImportMnemonic bool
+	keysImportSource   mnemonicSource
+	keysImportPassFile string
+	keysImportExpected string
+
+	keysDeleteYes bool
+
+	keysSplitSource         mnemonicSource
+	keysSplitGroupThreshold int
+	keysSplitGroups         []string
+
+	keysMigrateTo           string
+	keysMigrateToPassFile   string
+	keysMigrateDeleteSource bool
 )
 
 func init() {
 	keysAddCmd.Flags().StringVar(&keysAddHome, "home", "/sekai", "sekaid home directory")
 	keysAddCmd.Flags().StringVar(&keysAddName, "name", "genesis", "Key name")
 	keysAddCmd.Flags().StringVar(&keysAddBackend, "keyring-backend", "test", "Keyring backend (test|file|os)")
+	keysAddCmd.Flags().BoolVar(&keysAddRecover, "recover", false, "Recover the key from a mnemonic read from stdin")
+	keysAddCmd.Flags().IntVar(&keysAddWords, "words", keys.DefaultWords, "Length of a generated mnemonic (12|15|18|21|24)")
+	keysAddCmd.Flags().StringVar(&keysAddEntropyFile, "entropy-file", "", "File with hex entropy to encode as the mnemonic instead of random (16-32 bytes, - for stdin)")
+	keysAddCmd.Flags().IntVar(&keysAddEntropyFD, "entropy-fd", -1, "Read the hex entropy from this open file descriptor")
+	keysAddCmd.Flags().StringVar(&keysAddExtraEntropy, "extra-entropy", "", "Additional randomness mixed into the generated mnemonic")
+	keysAddCmd.Flags().StringVar(&keysAddExpected, "expected-address", "", "Refuse a recovered mnemonic unless it derives this kira1 or kiravaloper1 address")
+	keysAddMnemonic.register(keysAddCmd)
+	keysAddCmd.Flags().StringVar(&keysAddPassFile, "keyring-passphrase-file", "", "File with the file keyring passphrase (default $"+keyringPassphraseEnv+", then stdin)")
+
+	keysCmd.PersistentFlags().StringVar(&keysHome, "home", "/sekai", "sekaid home directory")
+	keysCmd.PersistentFlags().StringVar(&keysBackend, "keyring-backend", "test", "Keyring backend (test|file|os)")
+	keysCmd.PersistentFlags().StringVar(&keysPassFile, "keyring-passphrase-file", "", "File with the file keyring passphrase (default $"+keyringPassphraseEnv+", then stdin)")
+
+	for _, c := range []*cobra.Command{keysListCmd, keysShowCmd, keysImportCmd, keysMigrateCmd} {
+		c.Flags().BoolVar(&keysJSON, "json", false, "Print JSON")
+	}
+
+	keysExportCmd.Flags().StringVar(&keysExportOut, "out", "", "File to write the armored key to (default stdout)")
+	keysExportCmd.Flags().StringVar(&keysExportPassFile, "passphrase-file", "", "File with the passphrase encrypting the key (default $"+keyPassphraseEnv+", then stdin)")
+
+	keysImportCmd.Flags().StringVar(&keysImportArmor, "armor", "", "Armored private key file to import")
+	keysImportCmd.Flags().BoolVar(&keysImportMnemonic, "mnemonic", false, "Recover the key from a mnemonic read from stdin")
+	keysImportSource.register(keysImportCmd)
+	keysImportCmd.Flags().StringVar(&keysImportPassFile, "passphrase-file", "", "File with the passphrase of the armored key (default $"+keyPassphraseEnv+", then stdin)")
+	keysImportCmd.Flags().StringVar(&keysImportExpected, "expected-address", "", "Refuse the key unless it has this kira1 or kiravaloper1 address")
+
+	keysDeleteCmd.Flags().BoolVarP(&keysDeleteYes, "yes", "y", false, "Delete without asking for confirmation")
+
+	keysSplitSource.register(keysSplitCmd)
+	keysSplitCmd.Flags().IntVar(&keysSplitGroupThreshold, "group-threshold", 1, "Number of groups needed to recover the mnemonic")
+	keysSplitCmd.Flags().StringArrayVar(&keysSplitGroups, "group", []string{"2-of-3"}, "Group as member threshold and count, e.g. 2-of-3 (repeatable)")
+
+	keysMigrateCmd.Flags().StringVar(&keysMigrateTo, "to", "", "Destination keyring backend (test|file|os)")
+	keysMigrateCmd.Flags().StringVar(&keysMigrateToPassFile, "to-passphrase-file", "", "File with the destination file keyring passphrase (default $"+keyringPassphraseEnv+", then stdin)")
+	keysMigrateCmd.Flags().BoolVar(&keysMigrateDeleteSource, "delete-source", false, "Delete each key from the source keyring once migrated")
+	keysMigrateCmd.MarkFlagRequired("to")
+
+	keysCmd.AddCommand(keysListCmd, keysShowCmd, keysExportCmd, keysImportCmd, keysDeleteCmd, keysSplitCmd, keysMigrateCmd)
+}
+
+// keyOutput is a key as printed by the keys commands
+type keyOutput struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Address         string `json:"address"`
+	Valoper         string `json:"valoper"`
+	PubKey          string `json:"pubkey"`
+	Valcons         string `json:"valcons,omitempty"`
+	ConsensusPubKey string `json:"consensus_pubkey,omitempty"`
+}
+
+func newKeyOutput(info keyInfo) keyOutput {
+	return keyOutput{
+		Name:    info.Name,
+		Type:    info.Type,
+		Address: info.Address,
+		Valoper: info.valoper(),
+		PubKey:  info.pubKey(),
+	}
+}
+
+// readEntropy reads hex entropy from file, stdin ("-") or fd
+func readEntropy(file string, fd int) []byte {
+	if file != "" && fd >= 0 {
+		Fatal("Give only one of --entropy-file and --entropy-fd")
+	}
+	var data []byte
+	var err error
+	switch {
+	case file == "-" && pty.IsTerminal(os.Stdin):
+		data, err = readHiddenLine("Enter hex entropy: ", bytes.TrimSpace)
+	case file == "-":
+		data, err = readStdinLine(bytes.TrimSpace)
+	default:
+		data, err = readSecretInput(file, fd)
+	}
+	if err != nil {
+		Fatal("Failed to read entropy: %v", err)
+	}
+	defer zeroBytes(data)
+	data = bytes.TrimSpace(data)
+	entropy := make([]byte, hex.DecodedLen(len(data)))
+	if _, err := hex.Decode(entropy, data); err != nil {
+		Fatal("Invalid entropy: %v", err)
+	}
+	return entropy
 }
 
 func runKeysAdd(cmd *cobra.Command, args []string) {
+	if keysAddMnemonic.given() && !keysAddRecover {
+		Fatal("--mnemonic-file, --mnemonic-fd and --shamir need --recover")
+	}
+
+	var mnemonic string
+	var passphrase []byte
+	switch {
+	case keysAddRecover:
+		if keysAddEntropyFile != "" || keysAddEntropyFD >= 0 || keysAddExtraEntropy != "" {
+			Fatal("--entropy-file, --entropy-fd and --extra-entropy cannot be used with --recover")
+		}
+		line, p, err := keysAddMnemonic.read()
+		if err != nil {
+			Fatal("%v", err)
+		}
+		mnemonic, passphrase = keys.NormalizeMnemonic(string(line)), p
+		zeroBytes(line)
+	case keysAddEntropyFile != "" || keysAddEntropyFD >= 0:
+		entropy := readEntropy(keysAddEntropyFile, keysAddEntropyFD)
+		var err error
+		mnemonic, err = keys.MnemonicFromEntropy(entropy)
+		zeroBytes(entropy)
+		if err != nil {
+			Fatal("Invalid entropy: %v", err)
+		}
+	default:
+		m, err := keys.NewMnemonic(keysAddWords, []byte(keysAddExtraEntropy))
+		if err != nil {
+			Fatal("Failed to generate mnemonic: %v", err)
+		}
+		mnemonic = m
+	}
+	if !keysAddRecover {
+		var err error
+		if passphrase, err = keysAddMnemonic.readBIP39Passphrase(); err != nil {
+			Fatal("%v", err)
+		}
+	}
+	defer zeroBytes(passphrase)
+
+	key := deriveKey(mnemonic, passphrase, keysAddExpected)
+
+	kr, err := openKeyring(keysAddHome, keysAddBackend, keysAddPassFile)
+	if err != nil {
+		Fatal("%v", err)
+	}
+	defer kr.close()
+
 	Log("Adding key '%s' with keyring backend '%s'", keysAddName, keysAddBackend)
+	if err := kr.add(keysAddName, []byte(mnemonic), passphrase, key.AccAddress()); err != nil {
+		Fatal("Failed to add key: %v", err)
+	}
 
-	sekaidCmd := exec.Command("/sekaid", "keys", "add", keysAddName,
-		"--keyring-backend", keysAddBackend,
-		"--home", keysAddHome)
+	fmt.Printf("name: %s\naddress: %s\nvaloper: %s\n", keysAddName, key.AccAddress(), key.ValoperAddress())
+	if !keysAddRecover {
+		Log("Write down the mnemonic below: it is the only way to recover this key")
+		fmt.Printf("mnemonic: %s\n", mnemonic)
+	}
+}
 
-	output, err := sekaidCmd.CombinedOutput()
+// deriveKey validates mnemonic and derives its key with the BIP39
+// passphrase, checking it against expected if given. Only the public part of
+// the returned key is kept.
+func deriveKey(mnemonic string, passphrase []byte, expected string) *keys.Key {
+	var key *keys.Key
+	var err error
+	if expected != "" {
+		key, err = keys.CheckMnemonic(mnemonic, string(passphrase), "", expected)
+	} else {
+		key, err = keys.FromMnemonic(mnemonic, string(passphrase), "")
+	}
 	if err != nil {
-		Fatal("Failed to add key: %v\n%s", err, string(output))
+		Fatal("%v", err)
 	}
+	key.Zero()
+	return key
+}
+
+func runKeysList(cmd *cobra.Command, args []string) {
+	kr := mustOpenKeyring()
+	defer kr.close()
+
+	infos, err := kr.list()
+	if err != nil {
+		Fatal("Failed to list keys: %v", err)
+	}
+	list := make([]keyOutput, 0, len(infos))
+	for _, info := range infos {
+		list = append(list, newKeyOutput(info))
+	}
+
+	if keysJSON {
+		printJSON(list)
+		return
+	}
+	if len(list) == 0 {
+		Log("No keys in the %s keyring of %s", keysBackend, keysHome)
+		return
+	}
+	fmt.Printf("%-20s %-8s %s\n", "NAME", "TYPE", "ADDRESS")
+	for _, k := range list {
+		fmt.Printf("%-20s %-8s %s\n", k.Name, k.Type, k.Address)
+	}
+}
+
+func runKeysShow(cmd *cobra.Command, args []string) {
+	kr := mustOpenKeyring()
+	defer kr.close()
+
+	info, err := kr.show(args[0])
+	if err != nil {
+		Fatal("Failed to show key %s: %v", args[0], err)
+	}
+	out := newKeyOutput(info)
+
+	// The consensus key belongs to the node, which only the validator key
+	// operates
+	if info.Name == validatorKeyName {
+		if ck, err := keys.LoadConsensusKey(consensusKeyPath(keysHome)); err == nil {
+			out.Valcons = ck.ValconsAddress()
+			out.ConsensusPubKey = base64.StdEncoding.EncodeToString(ck.PubKey())
+			ck.Zero()
+		}
+	}
+
+	if keysJSON {
+		printJSON(out)
+		return
+	}
+	fmt.Printf("name: %s\ntype: %s\naddress: %s\nvaloper: %s\npubkey: %s\n",
+		out.Name, out.Type, out.Address, out.Valoper, out.PubKey)
+	if out.Valcons != "" {
+		fmt.Printf("valcons: %s\nconsensus_pubkey: %s\n", out.Valcons, out.ConsensusPubKey)
+	}
+}
+
+func runKeysExport(cmd *cobra.Command, args []string) {
+	kr := mustOpenKeyring()
+	defer kr.close()
 
-	Log("Key added successfully")
-	fmt.Print(string(output))
+	passphrase, err := readPassphrase(keysExportPassFile, keyPassphraseEnv, "key passphrase", minPassphrase, false)
+	if err != nil {
+		Fatal("Failed to read key passphrase: %v", err)
+	}
+	armor, err := kr.exportArmor(args[0], passphrase)
+	zeroBytes(passphrase)
+	if err != nil {
+		Fatal("Failed to export key %s: %v", args[0], err)
+	}
+
+	if keysExportOut == "" {
+		os.Stdout.Write(armor)
+		return
+	}
+	if err := config.WriteFileAtomic(keysExportOut, armor, 0600); err != nil {
+		Fatal("Failed to write %s: %v", keysExportOut, err)
+	}
+	Log("Exported key %s to %s", args[0], keysExportOut)
+}
+
+func runKeysImport(cmd *cobra.Command, args []string) {
+	name := args[0]
+	if keysImportSource.given() {
+		keysImportMnemonic = true
+	}
+	if (keysImportArmor == "") == !keysImportMnemonic {
+		Fatal("Give exactly one of --armor or --mnemonic")
+	}
+
+	var mnemonic, address string
+	var passphrase []byte
+	if keysImportMnemonic {
+		line, p, err := keysImportSource.read()
+		if err != nil {
+			Fatal("%v", err)
+		}
+		mnemonic, passphrase = keys.NormalizeMnemonic(string(line)), p
+		zeroBytes(line)
+		defer zeroBytes(passphrase)
+		address = deriveKey(mnemonic, passphrase, keysImportExpected).AccAddress()
+	}
+
+	kr := mustOpenKeyring()
+	defer kr.close()
+	if _, err := kr.show(name); err == nil {
+		Fatal("Key %s already exists in the %s keyring", name, keysBackend)
+	}
+
+	if keysImportMnemonic {
+		if err := kr.add(name, []byte(mnemonic), passphrase, address); err != nil {
+			Fatal("Failed to import key %s: %v", name, err)
+		}
+	} else {
+		armor, err := os.ReadFile(keysImportArmor)
+		if err != nil {
+			Fatal("Failed to read armored key: %v", err)
+		}
+		passphrase, err := readPassphrase(keysImportPassFile, keyPassphraseEnv, "key passphrase", minPassphrase, false)
+		if err != nil {
+			Fatal("Failed to read key passphrase: %v", err)
+		}
+		err = kr.importArmor(name, armor, passphrase)
+		zeroBytes(passphrase)
+		if err != nil {
+			Fatal("Failed to import key %s: %v", name, err)
+		}
+	}
+
+	info, err := kr.show(name)
+	if err != nil {
+		Fatal("Failed to show imported key %s: %v", name, err)
+	}
+	if keysImportArmor != "" && keysImportExpected != "" {
+		if err := matchAddress(info, keysImportExpected); err != nil {
+			kr.delete(name)
+			Fatal("Removed imported key: %v", err)
+		}
+	}
+	Log("Imported key %s", name)
+
+	out := newKeyOutput(info)
+	if keysJSON {
+		printJSON(out)
+		return
+	}
+	fmt.Printf("name: %s\naddress: %s\nvaloper: %s\n", out.Name, out.Address, out.Valoper)
+}
+
+func runKeysDelete(cmd *cobra.Command, args []string) {
+	name := args[0]
+	kr := mustOpenKeyring()
+	defer kr.close()
+
+	info, err := kr.show(name)
+	if err != nil {
+		Fatal("Failed to find key %s: %v", name, err)
+	}
+	if !keysDeleteYes {
+		fmt.Fprintf(os.Stderr, "Delete key %s (%s) from the %s keyring? [y/N] ", name, info.Address, keysBackend)
+		answer, err := readStdinLine(bytes.TrimSpace)
+		if err != nil || !(string(answer) == "y" || string(answer) == "yes") {
+			Fatal("Aborted")
+		}
+	}
+	if err := kr.delete(name); err != nil {
+		Fatal("Failed to delete key %s: %v", name, err)
+	}
+	Log("Deleted key %s (%s)", name, info.Address)
+}
+
+func runKeysSplit(cmd *cobra.Command, args []string) {
+	if keysSplitSource.shamir {
+		Fatal("--shamir cannot be used with keys split")
+	}
+	groups := make([]keys.ShareGroup, len(keysSplitGroups))
+	for i, g := range keysSplitGroups {
+		var threshold, count int
+		if _, err := fmt.Sscanf(g, "%d-of-%d", &threshold, &count); err != nil || fmt.Sprintf("%d-of-%d", threshold, count) != g {
+			Fatal("Invalid --group %q, expected e.g. 2-of-3", g)
+		}
+		groups[i] = keys.ShareGroup{Threshold: threshold, Count: count}
+	}
+
+	line, passphrase, err := keysSplitSource.read()
+	if err != nil {
+		Fatal("%v", err)
+	}
+	defer zeroBytes(passphrase)
+	mnemonic := keys.NormalizeMnemonic(string(line))
+	zeroBytes(line)
+	secret, err := keys.ShamirSecretFromMnemonic(mnemonic)
+	if err != nil {
+		Fatal("%v", err)
+	}
+	defer zeroBytes(secret)
+
+	shares, err := keys.SplitSecret(secret, passphrase, keysSplitGroupThreshold, groups)
+	if err != nil {
+		Fatal("Failed to split mnemonic: %v", err)
+	}
+	if err := checkShares(shares, keysSplitGroupThreshold, groups, passphrase, mnemonic); err != nil {
+		Fatal("Shares do not recover the mnemonic: %v", err)
+	}
+
+	Log("Write down the shares below: any %d of the %d groups recover the key", keysSplitGroupThreshold, len(groups))
+	for i, group := range shares {
+		fmt.Printf("group %d (%d-of-%d):\n", i+1, groups[i].Threshold, groups[i].Count)
+		for _, share := range group {
+			fmt.Println(share)
+		}
+	}
+}
+
+// checkShares recovers mnemonic from the first threshold shares of the
+// first groupThreshold groups
+func checkShares(shares [][]string, groupThreshold int, groups []keys.ShareGroup, passphrase []byte, mnemonic string) error {
+	var parsed []*keys.Share
+	for i, group := range shares[:groupThreshold] {
+		for _, share := range group[:groups[i].Threshold] {
+			s, err := keys.ParseShare(share)
+			if err != nil {
+				return err
+			}
+			parsed = append(parsed, s)
+		}
+	}
+	secret, err := keys.CombineShares(parsed, passphrase)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(secret)
+	recovered, err := keys.MnemonicFromShamirSecret(secret)
+	if err != nil {
+		return err
+	}
+	if recovered != mnemonic {
+		return fmt.Errorf("recovered a different mnemonic")
+	}
+	return nil
+}
+
+// migrateResult is a key moved by keys migrate
+type migrateResult struct {
+	Name          string `json:"name"`
+	Address       string `json:"address"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	SourceDeleted bool   `json:"source_deleted"`
+}
+
+func runKeysMigrate(cmd *cobra.Command, args []string) {
+	if keysMigrateTo == keysBackend {
+		Fatal("Source and destination keyring backend are both %s", keysBackend)
+	}
+	src := mustOpenKeyring()
+	defer src.close()
+	dst, err := openKeyring(keysHome, keysMigrateTo, keysMigrateToPassFile)
+	if err != nil {
+		Fatal("%v", err)
+	}
+	defer dst.close()
+
+	names := args
+	if len(names) == 0 {
+		infos, err := src.list()
+		if err != nil {
+			Fatal("Failed to list keys: %v", err)
+		}
+		for _, info := range infos {
+			names = append(names, info.Name)
+		}
+	}
+
+	var results []migrateResult
+	for _, name := range names {
+		info, err := src.show(name)
+		if err != nil {
+			Fatal("Failed to find key %s: %v", name, err)
+		}
+		if _, err := dst.show(name); err == nil {
+			Fatal("Key %s already exists in the %s keyring", name, keysMigrateTo)
+		}
+
+		if err := migrateKey(src, dst, info); err != nil {
+			Fatal("Failed to migrate key %s: %v", name, err)
+		}
+		r := migrateResult{Name: name, Address: info.Address, From: keysBackend, To: keysMigrateTo}
+		if keysMigrateDeleteSource {
+			if err := src.delete(name); err != nil {
+				Fatal("Key %s was migrated but could not be deleted from the %s keyring: %v", name, keysBackend, err)
+			}
+			r.SourceDeleted = true
+		}
+		Log("Migrated key %s (%s) from %s to %s", name, info.Address, keysBackend, keysMigrateTo)
+		results = append(results, r)
+	}
+
+	if keysJSON {
+		printJSON(results)
+	}
+}
+
+// migrateKey copies a key from src to dst through an armor encrypted with a
+// one-time passphrase and checks the address it has in dst
+func migrateKey(src, dst *keyring, info keyInfo) error {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	passphrase := []byte(hex.EncodeToString(secret))
+	zeroBytes(secret)
+	defer zeroBytes(passphrase)
+
+	armor, err := src.exportArmor(info.Name, passphrase)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(armor)
+	if err := dst.importArmor(info.Name, armor, passphrase); err != nil {
+		return err
+	}
+
+	imported, err := dst.show(info.Name)
+	if err != nil {
+		return err
+	}
+	if imported.Address != info.Address {
+		dst.delete(info.Name)
+		return fmt.Errorf("imported key has address %s, expected %s", imported.Address, info.Address)
+	}
+	return nil
+}
+
+// matchAddress fails unless expected is the account or operator address of info
+func matchAddress(info keyInfo, expected string) error {
+	if expected == info.Address || expected == info.valoper() {
+		return nil
+	}
+	return fmt.Errorf("key has address %s, expected %s", info.Address, expected)
+}
+
+func mustOpenKeyring() *keyring {
+	kr, err := openKeyring(keysHome, keysBackend, keysPassFile)
+	if err != nil {
+		Fatal("%v", err)
+	}
+	return kr
+}
+
+func printJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		Fatal("Failed to encode JSON: %v", err)
+	}
+	fmt.Println(string(out))
 }
@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"scaller/internal/keys"
+	"scaller/internal/pty"
+
+	"github.com/spf13/cobra"
+)
+
+// mnemonicFileEnv names a file holding the mnemonic, e.g. a Docker secret,
+// used when no other source is given
+const mnemonicFileEnv = "SCALLER_MNEMONIC_FILE"
+
+// bip39PassphraseEnv holds the BIP39 passphrase when no file is given
+const bip39PassphraseEnv = "SCALLER_BIP39_PASSPHRASE"
+
+// mnemonicSource holds the flags selecting where a mnemonic and its BIP39
+// passphrase are read from
+type mnemonicSource struct {
+	file           string
+	fd             int
+	shamir         bool
+	passphrase     bool
+	passphraseFile string
+}
+
+// register adds the mnemonic source flags to cmd
+func (m *mnemonicSource) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&m.file, "mnemonic-file", "", "Read the mnemonic from this file, e.g. a Docker secret under /run/secrets (default $"+mnemonicFileEnv+", then stdin)")
+	cmd.Flags().IntVar(&m.fd, "mnemonic-fd", -1, "Read the mnemonic from this open file descriptor")
+	cmd.Flags().BoolVar(&m.shamir, "shamir", false, "Recover the mnemonic from SLIP-39 Shamir shares, one per line, of a master secret \""+keys.ShamirSecretPrefix+"\" + BIP39 entropy")
+	cmd.Flags().BoolVar(&m.passphrase, "bip39-passphrase", false, "Also read a BIP39 passphrase (25th word) from $"+bip39PassphraseEnv+", the terminal or the next stdin line")
+	cmd.Flags().StringVar(&m.passphraseFile, "bip39-passphrase-file", "", "File with the BIP39 passphrase (implies --bip39-passphrase)")
+}
+
+// given reports whether a mnemonic source other than stdin was selected
+func (m *mnemonicSource) given() bool {
+	return m.file != "" || m.fd >= 0 || m.shamir
+}
+
+// read returns the mnemonic and the BIP39 passphrase (nil unless requested)
+// in buffers the caller zeroes after use. With --shamir the passphrase
+// decrypts the shares and the recovered mnemonic has none.
+func (m *mnemonicSource) read() (mnemonic, passphrase []byte, err error) {
+	if m.file != "" && m.fd >= 0 {
+		return nil, nil, fmt.Errorf("give only one of --mnemonic-file and --mnemonic-fd")
+	}
+	file := m.file
+	if file == "" && m.fd < 0 {
+		file = os.Getenv(mnemonicFileEnv)
+	}
+
+	var lines func(prompt string) ([]byte, error)
+	switch {
+	case file != "" || m.fd >= 0:
+		data, err := readSecretInput(file, m.fd)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read mnemonic: %w", err)
+		}
+		defer zeroBytes(data)
+		if !m.shamir {
+			// A file may spread the words over several lines
+			mnemonic = bytes.Join(bytes.Fields(data), []byte(" "))
+			break
+		}
+		r := bufio.NewReader(bytes.NewReader(data))
+		lines = func(string) ([]byte, error) { return readLine(r, bytes.TrimSpace) }
+	case pty.IsTerminal(os.Stdin):
+		lines = func(prompt string) ([]byte, error) { return readHiddenLine(prompt, bytes.TrimSpace) }
+	default:
+		if m.shamir {
+			Log("Reading SLIP-39 shares from stdin, one per line...")
+		} else {
+			Log("Reading mnemonic from stdin...")
+		}
+		lines = func(string) ([]byte, error) { return readStdinLine(bytes.TrimSpace) }
+	}
+
+	if mnemonic == nil {
+		if m.shamir {
+			mnemonic, err = readShares(lines, m)
+			return mnemonic, nil, err
+		}
+		if mnemonic, err = lines("Enter mnemonic: "); err != nil {
+			return nil, nil, fmt.Errorf("failed to read mnemonic: %w", err)
+		}
+	} else if m.shamir {
+		return nil, nil, fmt.Errorf("--shamir needs shares, one per line")
+	}
+
+	if passphrase, err = m.readBIP39Passphrase(); err != nil {
+		zeroBytes(mnemonic)
+		return nil, nil, err
+	}
+	return mnemonic, passphrase, nil
+}
+
+// readSecretInput reads a secret from file, or from the open file descriptor
+// fd if file is empty
+func readSecretInput(file string, fd int) ([]byte, error) {
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// readBIP39Passphrase reads the BIP39 passphrase if one was requested
+func (m *mnemonicSource) readBIP39Passphrase() ([]byte, error) {
+	if !m.passphrase && m.passphraseFile == "" {
+		return nil, nil
+	}
+	passphrase, err := readPassphrase(m.passphraseFile, bip39PassphraseEnv, "BIP39 passphrase", 1, true)
+	if err != nil {
+		return nil, err
+	}
+	// sekaid trims the passphrase it prompts for; refuse one it would change
+	if !bytes.Equal(passphrase, bytes.TrimSpace(passphrase)) {
+		zeroBytes(passphrase)
+		return nil, fmt.Errorf("BIP39 passphrase must not start or end with whitespace")
+	}
+	return passphrase, nil
+}
+
+// readShares reads SLIP-39 shares until they are enough to recover the
+// secret, and returns the BIP39 mnemonic of that secret
+func readShares(lines func(string) ([]byte, error), m *mnemonicSource) ([]byte, error) {
+	var shares []*keys.Share
+	for !keys.SharesComplete(shares) {
+		line, err := lines(fmt.Sprintf("Enter share %d: ", len(shares)+1))
+		if err != nil || len(line) == 0 {
+			return nil, fmt.Errorf("ran out of shares after %d; more are needed to recover the secret", len(shares))
+		}
+		share, err := keys.ParseShare(string(line))
+		zeroBytes(line)
+		if err != nil {
+			return nil, fmt.Errorf("share %d: %w", len(shares)+1, err)
+		}
+		shares = append(shares, share)
+	}
+	defer func() {
+		for _, s := range shares {
+			zeroBytes(s.Value)
+		}
+	}()
+
+	var passphrase []byte
+	if m.passphrase || m.passphraseFile != "" {
+		var err error
+		if passphrase, err = readPassphrase(m.passphraseFile, bip39PassphraseEnv, "SLIP-39 passphrase", 1, true); err != nil {
+			return nil, err
+		}
+		defer zeroBytes(passphrase)
+	}
+	secret, err := keys.CombineShares(shares, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+	mnemonic, err := keys.MnemonicFromShamirSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	Log("Recovered mnemonic from %d shares", len(shares))
+	return []byte(mnemonic), nil
+}
+
+// readHiddenLine prompts on stderr and reads a stdin line from the terminal
+// without echoing it
+func readHiddenLine(prompt string, trim func([]byte) []byte) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	restore, err := pty.NoEcho(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+	return readStdinLine(trim)
+}
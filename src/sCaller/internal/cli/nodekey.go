@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"scaller/internal/bundle"
+	"scaller/internal/keys"
+
+	"github.com/spf13/cobra"
+)
+
+var nodeKeyCmd = &cobra.Command{
+	Use:   "node-key",
+	Short: "Show, generate, import and back up the P2P node key",
+	Long: `Manages config/node_key.json, the ed25519 key that determines the node ID.
+Replacing it changes the node ID, so persistent_peers entries on other nodes
+pointing at this node must be updated. Commands that replace the key refuse
+to run while sekaid is running, and keep the previous key next to it as
+node_key.json.<time>.bak.`,
+}
+
+var nodeKeyShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the node ID and public key",
+	Args:  cobra.NoArgs,
+	Run:   runNodeKeyShow,
+}
+
+var nodeKeyGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a new node key",
+	Args:  cobra.NoArgs,
+	Run:   runNodeKeyGenerate,
+}
+
+var nodeKeyImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a node key",
+	Long: `Imports a node key from node_key.json, a base64 or raw ed25519 key, or a
+backup written by 'scaller node-key backup' (passphrase from
+--passphrase-file, $SCALLER_KEY_PASSPHRASE or stdin).
+
+Example:
+  scaller node-key import old/node_key.json --force`,
+	Args: cobra.ExactArgs(1),
+	Run:  runNodeKeyImport,
+}
+
+var nodeKeyBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Write an encrypted backup of the node key",
+	Long: `Encrypts node_key.json with a passphrase read from --passphrase-file,
+$SCALLER_KEY_PASSPHRASE or stdin (scrypt, AES-256-GCM).
+
+Example:
+  scaller node-key backup --out node.key.backup --passphrase-file /run/secrets/key`,
+	Args: cobra.NoArgs,
+	Run:  runNodeKeyBackup,
+}
+
+var (
+	nodeKeyHome     string
+	nodeKeyJSON     bool
+	nodeKeyForce    bool
+	nodeKeyPassFile string
+	nodeKeyOut      string
+)
+
+func init() {
+	nodeKeyCmd.PersistentFlags().StringVar(&nodeKeyHome, "home", "/sekai", "sekaid home directory")
+
+	nodeKeyShowCmd.Flags().BoolVar(&nodeKeyJSON, "json", false, "Print JSON")
+
+	for _, c := range []*cobra.Command{nodeKeyGenerateCmd, nodeKeyImportCmd} {
+		c.Flags().BoolVar(&nodeKeyForce, "force", false, "Replace an existing key")
+	}
+	for _, c := range []*cobra.Command{nodeKeyImportCmd, nodeKeyBackupCmd} {
+		c.Flags().StringVar(&nodeKeyPassFile, "passphrase-file", "", "File with the backup passphrase (default $"+keyPassphraseEnv+", then stdin)")
+	}
+
+	nodeKeyBackupCmd.Flags().StringVar(&nodeKeyOut, "out", "", "Backup file to write")
+	nodeKeyBackupCmd.MarkFlagRequired("out")
+
+	nodeKeyCmd.AddCommand(nodeKeyShowCmd, nodeKeyGenerateCmd, nodeKeyImportCmd, nodeKeyBackupCmd)
+}
+
+// nodeKeyOutput is the node key as printed by node-key show
+type nodeKeyOutput struct {
+	ID     string `json:"id"`
+	PubKey string `json:"pubkey"`
+}
+
+func nodeKeyPath(home string) string {
+	return filepath.Join(home, "config", "node_key.json")
+}
+
+func runNodeKeyShow(cmd *cobra.Command, args []string) {
+	nk, err := keys.LoadConsensusKey(nodeKeyPath(nodeKeyHome))
+	if err != nil {
+		Fatal("Failed to load node key: %v", err)
+	}
+	out := nodeKeyOutput{ID: nk.NodeID(), PubKey: base64.StdEncoding.EncodeToString(nk.PubKey())}
+	nk.Zero()
+
+	if nodeKeyJSON {
+		printJSON(out)
+		return
+	}
+	fmt.Printf("id: %s\npubkey: %s\n", out.ID, out.PubKey)
+}
+
+func runNodeKeyGenerate(cmd *cobra.Command, args []string) {
+	nk, err := keys.GenerateConsensusKey()
+	if err != nil {
+		Fatal("Failed to generate node key: %v", err)
+	}
+	replaceNodeKey(nk)
+}
+
+func runNodeKeyImport(cmd *cobra.Command, args []string) {
+	nk, _ := readKeyInput(args[0], bundle.KindNodeKey, nodeKeyPassFile)
+	replaceNodeKey(nk)
+}
+
+// replaceNodeKey installs nk as the node key of nodeKeyHome
+func replaceNodeKey(nk *keys.ConsensusKey) {
+	defer nk.Zero()
+	path := nodeKeyPath(nodeKeyHome)
+	requireStopped(nodeKeyHome, "replacing the node key")
+
+	if current, err := keys.LoadConsensusKey(path); err == nil {
+		id := current.NodeID()
+		current.Zero()
+		if id == nk.NodeID() {
+			Log("%s already holds node key %s", path, id)
+			fmt.Printf("id: %s\n", id)
+			return
+		}
+		if !nodeKeyForce {
+			Fatal("%s holds node key %s; use --force to replace it", path, id)
+		}
+		Log("Node ID changes from %s to %s; update persistent_peers on nodes that dial this one", id, nk.NodeID())
+	} else if !os.IsNotExist(err) && !nodeKeyForce {
+		Fatal("Failed to load %s: %v; use --force to replace it", path, err)
+	}
+
+	installKeyFile(path, nk.NodeKeyFile())
+	fmt.Printf("id: %s\n", nk.NodeID())
+}
+
+func runNodeKeyBackup(cmd *cobra.Command, args []string) {
+	path := nodeKeyPath(nodeKeyHome)
+	nk, err := keys.LoadConsensusKey(path)
+	if err != nil {
+		Fatal("Failed to load node key: %v", err)
+	}
+	id := nk.NodeID()
+	nk.Zero()
+	writeKeyBackup(bundle.KindNodeKey, id, path, nil, nodeKeyOut, nodeKeyPassFile)
+}
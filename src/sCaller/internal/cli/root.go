@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"scaller/internal/config"
+
 	"github.com/spf13/cobra"
 )
 
@@ -15,12 +17,22 @@ var rootCmd = &cobra.Command{
 Commands:
   wait                - Wait indefinitely (container entrypoint)
   init                - Initialize new sekaid node
-  keys-add            - Add key to keyring
+  keys-add            - Generate or recover a key into the keyring
+  keys                - List, show, export, import, delete, split and migrate keys
+  consensus-key       - Show, generate, import and back up the consensus key
+  node-key            - Show, generate, import and back up the node key
+  tmkms init          - Generate a TMKMS home for remote signing
+  signer              - Run a minimal remote signer for development and tests
   add-genesis-account - Add account to genesis
   gentx-claim         - Claim validator role in genesis
   join                - Initialize node and join existing network
   start               - Start sekaid (with optional restart)
-  status              - Show node and network status`,
+  status              - Show node and network status
+  snapshot create     - Archive node data for bootstrapping other nodes
+  topology generate   - Generate per-node configs from a sentry topology
+  config              - Get, set, unset, diff, show and trace node configuration
+  export              - Bundle config, node key and (encrypted) validator keys
+  import              - Restore a bundle onto a fresh home`,
 }
 
 func Execute() error {
@@ -28,14 +40,26 @@ func Execute() error {
 }
 
 func init() {
+	config.Logf = Log
+
 	rootCmd.AddCommand(waitCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(keysAddCmd)
+	rootCmd.AddCommand(keysCmd)
+	rootCmd.AddCommand(consensusKeyCmd)
+	rootCmd.AddCommand(nodeKeyCmd)
+	rootCmd.AddCommand(tmkmsCmd)
+	rootCmd.AddCommand(signerCmd)
 	rootCmd.AddCommand(addGenesisAccountCmd)
 	rootCmd.AddCommand(gentxClaimCmd)
 	rootCmd.AddCommand(joinCmd)
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(topologyCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"scaller/internal/config"
+	"scaller/internal/genesis"
+	"scaller/internal/keys"
+	"scaller/internal/node"
+	"scaller/internal/privval"
+
+	"github.com/spf13/cobra"
+)
+
+var signerCmd = &cobra.Command{
+	Use:   "signer",
+	Short: "Run a minimal remote signer for development and tests",
+	Long: `Connects to sekaid's priv_validator_laddr and answers its privval requests
+(public key, sign vote, sign proposal, ping) with a file-backed ed25519 key,
+so --remote-signer setups can be exercised without building TMKMS.
+
+tcp:// addresses use SecretConnection, unix:// sockets are plain, as in
+CometBFT. Each signature is recorded in --state before it is returned, and
+requests before or conflicting with the last signed height, round and step
+are refused. The state uses the format of priv_validator_state.json, so the
+node's own file can be shared. The privval protocol is that of CometBFT 0.34
+and 0.37 (TMKMS protocol_version v0.34); vote extensions are not signed, so
+with a CometBFT 0.38 sekaid, precommits asking for an extension signature
+are refused.
+
+--key is required once priv_validator_laddr is set: join --remote-signer
+removes config/priv_validator_key.json, and sekaid writes a new random key
+there when it starts, so signing with the default path would sign as an
+unrelated validator. Pass the key exported by 'scaller tmkms init' or a
+backup of the node's key.
+
+This is for development and tests. Production validators should use TMKMS
+with the key off the node (see TMKMS.md).
+
+Examples:
+  scaller signer --key /sekai/tmkms-export/secrets/priv_validator_key.json   # laddr and chain ID from /sekai
+  scaller signer --addr tcp://sekai.local:26659 --key ./priv_validator_key.json --state ./state.json`,
+	Args: cobra.NoArgs,
+	Run:  runSigner,
+}
+
+var (
+	signerHome    string
+	signerAddr    string
+	signerChainID string
+	signerKey     string
+	signerState   string
+	signerRetry   time.Duration
+)
+
+func init() {
+	signerCmd.Flags().StringVar(&signerHome, "home", "/sekai", "sekaid home directory for the defaults")
+	signerCmd.Flags().StringVar(&signerAddr, "addr", "", "sekaid priv_validator_laddr to connect to (default from config.toml, an unspecified host as 127.0.0.1)")
+	signerCmd.Flags().StringVar(&signerChainID, "chain-id", "", "Chain ID (default from genesis.json)")
+	signerCmd.Flags().StringVar(&signerKey, "key", "", "Consensus key: priv_validator_key.json or TMKMS softsign (default <home>/config/priv_validator_key.json unless priv_validator_laddr is set)")
+	signerCmd.Flags().StringVar(&signerState, "state", "", "Signing state file (default <home>/data/priv_validator_state.json)")
+	signerCmd.Flags().DurationVar(&signerRetry, "retry", time.Second, "Delay before reconnecting to sekaid")
+}
+
+func runSigner(cmd *cobra.Command, args []string) {
+	chainID := signerChainID
+	if chainID == "" {
+		var err error
+		if chainID, err = genesis.ChainID(filepath.Join(signerHome, "config", "genesis.json")); err != nil {
+			Fatal("Failed to read chain ID: %v (use --chain-id)", err)
+		}
+	}
+
+	v, _, _ := config.GetValue(signerHome, "config.priv_validator_laddr")
+	laddr, _ := v.(string)
+	addr := signerAddr
+	if addr == "" {
+		if laddr == "" {
+			Fatal("priv_validator_laddr is not set in %s; use --addr", signerHome)
+		}
+		var err error
+		if addr, err = localSignerAddr(laddr); err != nil {
+			Fatal("%v", err)
+		}
+	}
+
+	keyPath := signerKey
+	if keyPath == "" {
+		// In remote signer mode sekaid regenerates this file with a random key
+		if laddr != "" {
+			Fatal("priv_validator_laddr is set in %s, so %s is not the validator's key; use --key with the key exported by 'scaller tmkms init' or a backup", signerHome, consensusKeyPath(signerHome))
+		}
+		keyPath = consensusKeyPath(signerHome)
+	}
+	ck, err := keys.LoadConsensusKey(keyPath)
+	if err != nil {
+		Fatal("Failed to load consensus key: %v", err)
+	}
+	defer ck.Zero()
+
+	statePath := signerState
+	if statePath == "" {
+		statePath = node.SignStatePath(signerHome)
+	}
+	s, err := privval.NewSigner(chainID, ck, statePath)
+	if err != nil {
+		Fatal("Failed to load signing state: %v", err)
+	}
+	s.Logf = Log
+	s.VoteExtensions = detectProtocolVersion() == "v0.38"
+
+	Log("Warning: scaller signer is for development and tests; use TMKMS for production validators")
+	Log("Signing for %s on %s as %s from %s", chainID, addr, ck.ValconsAddress(), s.State())
+	for {
+		err := s.DialAndServe(addr)
+		Log("Signer connection to %s ended: %v; retrying in %s", addr, err, signerRetry)
+		time.Sleep(signerRetry)
+	}
+}
+
+// localSignerAddr turns a listen address into one a signer on the same
+// host dials
+func localSignerAddr(laddr string) (string, error) {
+	u, err := url.Parse(laddr)
+	if err != nil {
+		return "", fmt.Errorf("invalid priv_validator_laddr %q: %w", laddr, err)
+	}
+	if u.Scheme != "tcp" {
+		return laddr, nil
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return "tcp://" + net.JoinHostPort(host, u.Port()), nil
+}
@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"scaller/internal/node"
+	"scaller/internal/snapshot"
+
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Create and manage data snapshots",
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a snapshot of the node data directory",
+	Long: `Stops sekaid, archives data/ (without priv_validator_state.json) into a
+gzip-compressed tarball, writes a JSON manifest, restarts sekaid and prunes
+old snapshots.
+
+If sekaid runs under 'scaller start --restart', the restart loop is paused
+while the archive is written and resumes on its own. Otherwise sekaid is
+started again in the background with output appended to <home>/sekaid.log.
+
+The height and app hash are queried just before sekaid is stopped and the
+height checked against the last one committed in the consensus WAL; if a
+block was committed in between, sekaid is restarted and the snapshot
+refused, to be tried again. A node that is not running is archived with the
+height of its consensus WAL and no app hash; if the RPC still answers
+without a matching sekaid process, the snapshot is refused.
+
+Example:
+  scaller snapshot create --dir /sekai/snapshots --keep 3`,
+	Run: runSnapshotCreate,
+}
+
+var (
+	snapshotHome        string
+	snapshotDir         string
+	snapshotRPC         string
+	snapshotKeep        int
+	snapshotMaxAge      time.Duration
+	snapshotStopTimeout time.Duration
+	snapshotNoRestart   bool
+)
+
+func init() {
+	snapshotCreateCmd.Flags().StringVar(&snapshotHome, "home", "/sekai", "sekaid home directory")
+	snapshotCreateCmd.Flags().StringVar(&snapshotDir, "dir", "", "Snapshot output directory (default <home>/snapshots)")
+	snapshotCreateCmd.Flags().StringVar(&snapshotRPC, "rpc", "http://localhost:26657", "Sekai RPC address")
+	snapshotCreateCmd.Flags().IntVar(&snapshotKeep, "keep", 3, "Number of snapshots to keep (0 = unlimited)")
+	snapshotCreateCmd.Flags().DurationVar(&snapshotMaxAge, "max-age", 0, "Remove snapshots older than this (e.g. 168h, 0 = no limit)")
+	snapshotCreateCmd.Flags().DurationVar(&snapshotStopTimeout, "stop-timeout", 60*time.Second, "Time to wait for sekaid to stop before killing it")
+	snapshotCreateCmd.Flags().BoolVar(&snapshotNoRestart, "no-restart", false, "Leave sekaid stopped after the snapshot")
+
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+}
+
+// nodeStatus holds the fields of RPC /status recorded in a snapshot manifest
+type nodeStatus struct {
+	ChainID string
+	Height  int64
+	AppHash string
+}
+
+func runSnapshotCreate(cmd *cobra.Command, args []string) {
+	dir := snapshotDir
+	if dir == "" {
+		dir = filepath.Join(snapshotHome, "snapshots")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		Fatal("Failed to create snapshot directory: %v", err)
+	}
+
+	proc, err := node.FindSekaid(snapshotHome)
+	if err != nil {
+		Fatal("Failed to look up sekaid process: %v", err)
+	}
+
+	var status *nodeStatus
+	if proc != nil {
+		if err := node.Pause(snapshotHome); err != nil {
+			Fatal("Failed to pause restart loop: %v", err)
+		}
+		// Query just before stopping, then check no block was committed since
+		status, err = fetchNodeStatus(snapshotRPC)
+		if err != nil {
+			node.Resume(snapshotHome)
+			Fatal("Failed to query node status: %v", err)
+		}
+		Log("Stopping sekaid (pid %d)...", proc.PID)
+		if err := proc.Stop(snapshotStopTimeout); err != nil {
+			node.Resume(snapshotHome)
+			Fatal("Failed to stop sekaid: %v", err)
+		}
+		Log("sekaid stopped")
+
+		committed, err := node.CommittedHeight(snapshotHome)
+		if err == nil && committed != status.Height {
+			err = fmt.Errorf("sekaid committed height %d after reporting %d; try again", committed, status.Height)
+		}
+		if err != nil {
+			restartAfterSnapshot(proc)
+			Fatal("Cannot confirm the snapshot height: %v", err)
+		}
+	} else {
+		// An answering RPC means sekaid runs under a command line FindSekaid
+		// does not recognise; its data/ cannot be archived while it writes
+		if _, err := fetchNodeStatus(snapshotRPC); err == nil {
+			Fatal("sekaid answers on %s but no 'sekaid start --home %s' process was found; stop it first", snapshotRPC, snapshotHome)
+		}
+		Log("sekaid not running, reading the chain ID from genesis and the height from the consensus WAL")
+		status = &nodeStatus{ChainID: readGenesisChainID(snapshotHome)}
+		if status.Height, err = node.CommittedHeight(snapshotHome); err != nil {
+			Log("Warning: Cannot determine the committed height: %v", err)
+		}
+	}
+	Log("Snapshot of %s at height %d", status.ChainID, status.Height)
+
+	manifest, err := writeSnapshot(dir, status)
+
+	if proc != nil {
+		if snapshotNoRestart {
+			Log("sekaid left stopped; remove %s to let the supervisor restart it", node.PausePath(snapshotHome))
+		} else {
+			restartAfterSnapshot(proc)
+		}
+	}
+
+	if err != nil {
+		Fatal("Failed to create snapshot: %v", err)
+	}
+	Log("Snapshot written: %s (%d bytes, sha256 %s)", manifest.File, manifest.Size, manifest.SHA256)
+
+	removed, err := snapshot.Prune(dir, snapshotKeep, snapshotMaxAge)
+	if err != nil {
+		Fatal("Failed to prune snapshots: %v", err)
+	}
+	for _, name := range removed {
+		Log("Pruned old snapshot %s", name)
+	}
+
+	out, _ := json.MarshalIndent(manifest, "", "  ")
+	fmt.Println(string(out))
+}
+
+func writeSnapshot(dir string, status *nodeStatus) (*snapshot.Manifest, error) {
+	now := time.Now().UTC()
+	file := snapshot.Name(status.ChainID, status.Height, now) + ".tar.gz"
+
+	Log("Archiving %s...", filepath.Join(snapshotHome, "data"))
+	size, sum, err := snapshot.Create(filepath.Join(snapshotHome, "data"), filepath.Join(dir, file))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &snapshot.Manifest{
+		ChainID:   status.ChainID,
+		Height:    status.Height,
+		AppHash:   status.AppHash,
+		File:      file,
+		Size:      size,
+		SHA256:    sum,
+		CreatedAt: now,
+	}
+	if err := snapshot.WriteManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// restartAfterSnapshot hands sekaid back to its supervisor, or starts it
+// in the background when nothing else will
+func restartAfterSnapshot(proc *node.Process) {
+	supervised := proc.Supervised()
+	if err := node.Resume(snapshotHome); err != nil {
+		Log("Warning: Failed to remove pause marker: %v", err)
+	}
+	if supervised {
+		Log("Resumed supervisor, sekaid will restart")
+		return
+	}
+
+	// Restart with the stopped process's own flags
+	args := []string{"start", "--home", snapshotHome}
+	if len(proc.Args) > 1 {
+		args = proc.Args[1:]
+	}
+	logPath := filepath.Join(snapshotHome, "sekaid.log")
+	pid, err := node.StartDetached(sekaidPath, args, logPath)
+	if err != nil {
+		Log("Warning: Failed to restart sekaid: %v", err)
+		return
+	}
+	Log("Restarted sekaid (pid %d), logging to %s", pid, logPath)
+}
+
+// fetchNodeStatus reads chain-id, height and app hash from RPC /status
+func fetchNodeStatus(rpcAddr string) (*nodeStatus, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(rpcAddr + "/status")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var status struct {
+		Result struct {
+			NodeInfo struct {
+				Network string `json:"network"`
+			} `json:"node_info"`
+			SyncInfo struct {
+				LatestBlockHeight string `json:"latest_block_height"`
+				LatestAppHash     string `json:"latest_app_hash"`
+			} `json:"sync_info"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, err
+	}
+
+	height, err := strconv.ParseInt(status.Result.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid height %q", status.Result.SyncInfo.LatestBlockHeight)
+	}
+
+	return &nodeStatus{
+		ChainID: status.Result.NodeInfo.Network,
+		Height:  height,
+		AppHash: status.Result.SyncInfo.LatestAppHash,
+	}, nil
+}
+
+// readGenesisChainID returns chain_id from config/genesis.json, or "unknown"
+func readGenesisChainID(home string) string {
+	data, err := os.ReadFile(filepath.Join(home, "config", "genesis.json"))
+	if err != nil {
+		return "unknown"
+	}
+	var gen struct {
+		ChainID string `json:"chain_id"`
+	}
+	if err := json.Unmarshal(data, &gen); err != nil || gen.ChainID == "" {
+		return "unknown"
+	}
+	return gen.ChainID
+}
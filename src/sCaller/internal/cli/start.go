@@ -7,6 +7,9 @@ import (
 	"syscall"
 	"time"
 
+	"scaller/internal/config"
+	"scaller/internal/node"
+
 	"github.com/spf13/cobra"
 )
 
@@ -19,25 +22,47 @@ var startCmd = &cobra.Command{
 
 With --restart flag, runs sekaid as a subprocess and restarts on failure.
 
+Before every start the config files are compared with the overrides scaller
+last applied (see 'scaller config check'). --config-drift decides what
+happens to drift: warn logs it, enforce re-applies the desired values,
+ignore skips the check.
+
+In remote signer mode, a priv_validator_laddr reachable on a public address
+is refused before every start, as join refuses it, unless
+--allow-public-signer is given.
+
 Examples:
   scaller start                    # Start once (replaces process)
   scaller start --restart 5        # Restart up to 5 times on failure
-  scaller start --restart always   # Restart indefinitely (max 10 retries)`,
+  scaller start --restart always   # Restart indefinitely (max 10 retries)
+  scaller start --config-drift enforce`,
 	Run: runStart,
 }
 
 var (
-	startHome    string
-	startRestart string
+	startHome        string
+	startRestart     string
+	startConfigDrift string
+	startAllowExport bool
+	startAllowPublic bool
 )
 
 func init() {
 	startCmd.Flags().StringVar(&startHome, "home", "/sekai", "sekaid home directory")
 	startCmd.Flags().StringVar(&startRestart, "restart", "", "Restart on failure: number (1-10) or 'always' (max 10)")
+	startCmd.Flags().BoolVar(&startAllowExport, "allow-exported-key", false, "Start even though the validator key was exported with 'scaller export --include-keys'")
+	startCmd.Flags().BoolVar(&startAllowPublic, "allow-public-signer", false, "Start even though priv_validator_laddr listens on a public address")
+	startCmd.Flags().StringVar(&startConfigDrift, "config-drift", "warn", "Config drift handling before start: warn|enforce|ignore")
 }
 
 func runStart(cmd *cobra.Command, args []string) {
 	Log("Starting sekaid with home=%s", startHome)
+	checkKeyExported(startHome, startAllowExport)
+	switch startConfigDrift {
+	case "warn", "enforce", "ignore":
+	default:
+		Fatal("Invalid --config-drift value: %s (use warn, enforce or ignore)", startConfigDrift)
+	}
 
 	// If restart is not set, use syscall.Exec (original behavior)
 	if startRestart == "" {
@@ -54,6 +79,8 @@ func runStart(cmd *cobra.Command, args []string) {
 
 // execSekaid replaces the current process with sekaid
 func execSekaid() {
+	checkConfigDrift()
+	checkStartSignerLaddr()
 	argv := []string{"sekaid", "start", "--home", startHome}
 	env := os.Environ()
 
@@ -63,6 +90,62 @@ func execSekaid() {
 	}
 }
 
+// checkStartSignerLaddr refuses a public remote signer laddr, which config
+// edits after join may have introduced
+func checkStartSignerLaddr() {
+	v, _, err := config.GetValue(startHome, "config.priv_validator_laddr")
+	if err != nil {
+		Log("Warning: Failed to read priv_validator_laddr: %v", err)
+		return
+	}
+	if laddr, _ := v.(string); laddr != "" {
+		checkSignerLaddr(laddr, startAllowPublic)
+	}
+}
+
+// checkConfigDrift compares the config files with the desired state and,
+// depending on --config-drift, logs or re-applies drifted keys
+func checkConfigDrift() {
+	if startConfigDrift == "ignore" {
+		return
+	}
+	desired, err := config.LoadDesired(startHome)
+	if err != nil {
+		Log("Warning: Failed to load desired config state: %v", err)
+		return
+	}
+	if len(desired.Values) == 0 {
+		return
+	}
+
+	if startConfigDrift == "enforce" {
+		drifts, err := desired.Enforce(startHome)
+		if err != nil {
+			Fatal("Failed to enforce desired config: %v", err)
+		}
+		for _, d := range drifts {
+			Log("Config drift: %s was %s, restored %s", d.Key, driftGot(d), d.Want)
+		}
+		return
+	}
+
+	drifts, err := desired.Check(startHome)
+	if err != nil {
+		Log("Warning: Failed to check config drift: %v", err)
+		return
+	}
+	for _, d := range drifts {
+		Log("Warning: Config drift: %s is %s, desired %s", d.Key, driftGot(d), d.Want)
+	}
+}
+
+func driftGot(d config.Drift) string {
+	if d.Missing {
+		return "(unset)"
+	}
+	return d.Got
+}
+
 // parseRestartMode parses the restart flag value
 func parseRestartMode(mode string) int {
 	if mode == "always" {
@@ -87,6 +170,9 @@ func runWithRestart(maxRetries int) {
 	backoffSeconds := []int{1, 2, 5, 10, 15, 30, 30, 30, 30, 30} // Progressive backoff
 
 	for {
+		waitWhilePaused()
+		checkConfigDrift()
+		checkStartSignerLaddr()
 		Log("Starting sekaid (attempt %d/%d)...", retryCount+1, maxRetries)
 
 		cmd := exec.Command(sekaidPath, "start", "--home", startHome)
@@ -98,6 +184,12 @@ func runWithRestart(maxRetries int) {
 		err := cmd.Run()
 		runDuration := time.Since(startTime)
 
+		// Stopped on purpose (e.g. snapshot create): wait and restart without counting a retry
+		if node.IsPaused(startHome) {
+			Log("sekaid stopped while paused (%v)", err)
+			continue
+		}
+
 		if err == nil {
 			Log("sekaid exited normally")
 			return
@@ -122,3 +214,15 @@ func runWithRestart(maxRetries int) {
 		time.Sleep(time.Duration(backoff) * time.Second)
 	}
 }
+
+// waitWhilePaused blocks while the pause marker exists under startHome
+func waitWhilePaused() {
+	if !node.IsPaused(startHome) {
+		return
+	}
+	Log("sekaid paused, waiting for %s to be removed...", node.PausePath(startHome))
+	for node.IsPaused(startHome) {
+		time.Sleep(time.Second)
+	}
+	Log("Pause lifted")
+}
@@ -7,24 +7,39 @@ import (
 	"net/http"
 	"time"
 
+	"scaller/internal/bundle"
+	"scaller/internal/config"
+	"scaller/internal/node"
+
 	"github.com/spf13/cobra"
 )
 
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show node and network status",
-	Long:  `Displays a concise status table showing sekai, interx, and network health.`,
-	Run:   runStatus,
+	Long: `Displays a concise status table showing sekai, interx, and network health.
+
+When config.toml sets priv_validator_laddr (remote signer mode), the Signer
+row reports:
+  UP     a signer is connected and the validator signed within --signer-blocks
+  STALE  a signer is connected but the validator signed none of the last
+         --signer-blocks blocks
+  DOWN   no signer is connected, so the validator is not signing`,
+	Run: runStatus,
 }
 
 var (
-	statusRPCAddr   string
-	statusInterxAddr string
+	statusRPCAddr      string
+	statusInterxAddr   string
+	statusHome         string
+	statusSignerBlocks int64
 )
 
 func init() {
 	statusCmd.Flags().StringVar(&statusRPCAddr, "rpc", "http://localhost:26657", "Sekai RPC address")
 	statusCmd.Flags().StringVar(&statusInterxAddr, "interx", "http://proxy.local:8080", "Interx address")
+	statusCmd.Flags().StringVar(&statusHome, "home", "/sekai", "sekaid home directory (for config drift and remote signer detection)")
+	statusCmd.Flags().Int64Var(&statusSignerBlocks, "signer-blocks", 20, "Recent blocks (1-"+fmt.Sprint(maxSignerBlocks)+") to look for the validator's signature in remote signer mode")
 }
 
 // Status check results
@@ -35,6 +50,9 @@ type statusResult struct {
 }
 
 func runStatus(cmd *cobra.Command, args []string) {
+	if statusSignerBlocks < 1 || statusSignerBlocks > maxSignerBlocks {
+		Fatal("--signer-blocks must be between 1 and %d", maxSignerBlocks)
+	}
 	results := []statusResult{}
 
 	// Check Sekai RPC
@@ -49,6 +67,15 @@ func runStatus(cmd *cobra.Command, args []string) {
 	netStatus := getNetworkStatus(statusRPCAddr)
 	results = append(results, netStatus...)
 
+	// Check the remote signer connection and recent signatures
+	if signerStatus, signerDetail, ok := checkSigner(statusHome, statusRPCAddr, statusSignerBlocks); ok {
+		results = append(results, statusResult{"Signer", signerStatus, signerDetail})
+	}
+
+	// Compare config files with the last applied overrides
+	configStatus, configDetail := checkConfigState(statusHome)
+	results = append(results, statusResult{"Config", configStatus, configDetail})
+
 	// Print table
 	printStatusTable(results)
 }
@@ -91,6 +118,79 @@ func checkSekai(rpcAddr string) (string, string) {
 	return "OK", fmt.Sprintf("height %s", status.Result.SyncInfo.LatestBlockHeight)
 }
 
+// maxSignerBlocks bounds --signer-blocks, as each block is one /commit request
+const maxSignerBlocks = 100
+
+// checkSigner reports the remote signer's health, or ok false if the node
+// signs with a local key
+func checkSigner(home, rpcAddr string, blocks int64) (status, detail string, ok bool) {
+	v, _, err := config.GetValue(home, "config.priv_validator_laddr")
+	laddr, _ := v.(string)
+	if err != nil || laddr == "" {
+		return "", "", false
+	}
+
+	conns, err := node.SignerConnections(laddr)
+	if err != nil {
+		return "ERROR", err.Error(), true
+	}
+	if conns == 0 {
+		return "DOWN", fmt.Sprintf("no signer connected on %s", laddr), true
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(rpcAddr + "/status")
+	if err != nil {
+		return "UP", "connected, cannot fetch signatures", true
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "UP", fmt.Sprintf("connected, cannot fetch signatures: HTTP %d", resp.StatusCode), true
+	}
+
+	var st struct {
+		Result struct {
+			ValidatorInfo struct {
+				Address     string `json:"address"`
+				VotingPower string `json:"voting_power"`
+			} `json:"validator_info"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil || st.Result.ValidatorInfo.Address == "" {
+		return "UP", "connected, cannot fetch signatures", true
+	}
+	if vp := st.Result.ValidatorInfo.VotingPower; vp == "" || vp == "0" {
+		return "UP", "connected, not in active set", true
+	}
+
+	height, err := bundle.RecentlySigned(rpcAddr, st.Result.ValidatorInfo.Address, blocks)
+	if err != nil {
+		return "UP", fmt.Sprintf("connected, cannot check signatures: %v", err), true
+	}
+	if height == 0 {
+		return "STALE", fmt.Sprintf("connected, but missed the last %d blocks", blocks), true
+	}
+	return "UP", fmt.Sprintf("connected, signed block %d", height), true
+}
+
+func checkConfigState(home string) (string, string) {
+	desired, err := config.LoadDesired(home)
+	if err != nil {
+		return "ERROR", err.Error()
+	}
+	if len(desired.Values) == 0 {
+		return "N/A", "no applied overrides recorded"
+	}
+	drifts, err := desired.Check(home)
+	if err != nil {
+		return "ERROR", err.Error()
+	}
+	if len(drifts) > 0 {
+		return "DRIFT", fmt.Sprintf("%d key(s) changed, see 'scaller config check'", len(drifts))
+	}
+	return "OK", "matches applied overrides"
+}
+
 func checkInterx(addr string) (string, string) {
 	client := &http.Client{Timeout: 5 * time.Second}
 	resp, err := client.Get(addr + "/api/status")
@@ -205,11 +305,11 @@ func printStatusTable(results []statusResult) {
 
 func getStatusIcon(status string) string {
 	switch status {
-	case "OK":
+	case "OK", "UP":
 		return "[+]"
 	case "DOWN", "ERROR":
 		return "[X]"
-	case "WARN", "SYNCING":
+	case "WARN", "SYNCING", "DRIFT", "STALE":
 		return "[!]"
 	default:
 		return "[ ]"
@@ -0,0 +1,321 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"scaller/internal/config"
+	"scaller/internal/genesis"
+	"scaller/internal/keys"
+	"scaller/internal/node"
+	"scaller/internal/tmkms"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultSignerLaddr is where sekaid listens for a remote signer
+const defaultSignerLaddr = "tcp://0.0.0.0:26659"
+
+// defaultSignerHost is the sekai container's hostname on the compose network
+const defaultSignerHost = "sekai.local"
+
+var tmkmsCmd = &cobra.Command{
+	Use:   "tmkms",
+	Short: "Set up TMKMS remote signing",
+}
+
+var tmkmsInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Generate tmkms.toml, the KMS identity key and signing state",
+	Long: `Writes a TMKMS home into --out matching the node in --home:
+
+  tmkms.toml                         chain, softsign provider and validator
+                                     sections for the chain ID of genesis.json
+  secrets/priv_validator_key.json    the node's consensus key in softsign format
+                                     (or a new one with --generate-key)
+  secrets/kms-identity.key           KMS identity key (kept if present)
+  state/<chain-id>-consensus.json    seeded from priv_validator_state.json
+
+tmkms dials --addr, by default --laddr with an unspecified host replaced by
+sekai.local. --laddr defaults to the node's priv_validator_laddr or
+` + defaultSignerLaddr + `, and is set on the node if it differs; one listening on
+a public address needs --allow-public-signer. For a unix:// socket tmkms
+dials the same path, so both must share it.
+
+protocol_version follows the CometBFT version of 'sekaid version --long':
+v0.34 for 0.34 and 0.37, v0.38 from 0.38 on.
+
+sekaid must be stopped, since it keeps signing past the seeded state
+otherwise; --allow-running seeds it anyway, and re-running init after
+stopping sekaid raises the state file to the final height. An existing
+state file is only ever raised, and a different consensus key or tmkms.toml
+in --out is only replaced with --force. Once tmkms signs, remove
+config/priv_validator_key.json from the node.
+
+Examples:
+  scaller tmkms init --out /tmkms-export
+  scaller tmkms init --out ./tmkms --addr tcp://10.200.0.1:26659`,
+	Args: cobra.NoArgs,
+	Run:  runTmkmsInit,
+}
+
+var (
+	tmkmsHome            string
+	tmkmsOut             string
+	tmkmsChainID         string
+	tmkmsLaddr           string
+	tmkmsAddr            string
+	tmkmsKMSHome         string
+	tmkmsProtocolVersion string
+	tmkmsGenerateKey     bool
+	tmkmsForce           bool
+	tmkmsAllowRunning    bool
+	tmkmsAllowPublic     bool
+)
+
+func init() {
+	tmkmsInitCmd.Flags().StringVar(&tmkmsHome, "home", "/sekai", "sekaid home directory")
+	tmkmsInitCmd.Flags().StringVar(&tmkmsOut, "out", "", "Directory to write the TMKMS home into")
+	tmkmsInitCmd.Flags().StringVar(&tmkmsChainID, "chain-id", "", "Chain ID (default from genesis.json)")
+	tmkmsInitCmd.Flags().StringVar(&tmkmsLaddr, "laddr", "", "sekaid priv_validator_laddr (default from config.toml, then "+defaultSignerLaddr+")")
+	tmkmsInitCmd.Flags().StringVar(&tmkmsAddr, "addr", "", "Address tmkms dials (default --laddr with an unspecified host replaced by "+defaultSignerHost+")")
+	tmkmsInitCmd.Flags().StringVar(&tmkmsKMSHome, "kms-home", tmkms.DefaultHome, "TMKMS home inside its container, used for paths in tmkms.toml")
+	tmkmsInitCmd.Flags().StringVar(&tmkmsProtocolVersion, "protocol-version", "", "privval protocol_version (default from sekaid's CometBFT version)")
+	tmkmsInitCmd.Flags().BoolVar(&tmkmsGenerateKey, "generate-key", false, "Generate a new consensus key instead of converting the node's")
+	tmkmsInitCmd.Flags().BoolVar(&tmkmsForce, "force", false, "Replace a different consensus key or tmkms.toml in --out")
+	tmkmsInitCmd.Flags().BoolVar(&tmkmsAllowRunning, "allow-running", false, "Seed the signing state while sekaid runs; re-run init after stopping it")
+	tmkmsInitCmd.Flags().BoolVar(&tmkmsAllowPublic, "allow-public-signer", false, "Allow a --laddr that listens on a public address")
+	tmkmsInitCmd.MarkFlagRequired("out")
+
+	tmkmsCmd.AddCommand(tmkmsInitCmd)
+}
+
+func runTmkmsInit(cmd *cobra.Command, args []string) {
+	// A running sekaid signs past the state seeded here
+	if tmkmsAllowRunning {
+		if proc, err := node.FindSekaid(tmkmsHome); err == nil && proc != nil {
+			Log("Warning: sekaid is running (pid %d); re-run tmkms init after stopping it to seed the final state", proc.PID)
+		}
+	} else {
+		requireStopped(tmkmsHome, "seeding the tmkms signing state (or pass --allow-running)")
+	}
+
+	chainID := tmkmsChainID
+	if chainID == "" {
+		var err error
+		if chainID, err = genesis.ChainID(filepath.Join(tmkmsHome, "config", "genesis.json")); err != nil {
+			Fatal("Failed to read chain ID: %v (use --chain-id)", err)
+		}
+	}
+
+	laddr := tmkmsLaddr
+	current, _, _ := config.GetValue(tmkmsHome, "config.priv_validator_laddr")
+	currentLaddr, _ := current.(string)
+	if laddr == "" {
+		laddr = currentLaddr
+	}
+	if laddr == "" {
+		laddr = defaultSignerLaddr
+	}
+	checkSignerLaddr(laddr, tmkmsAllowPublic)
+	addr := tmkmsAddr
+	if addr == "" {
+		var err error
+		if addr, err = signerDialAddr(laddr); err != nil {
+			Fatal("%v", err)
+		}
+	}
+
+	protocol := tmkmsProtocolVersion
+	if protocol == "" {
+		protocol = detectProtocolVersion()
+	}
+
+	ck := tmkmsConsensusKey()
+	defer ck.Zero()
+
+	cfg := tmkms.Config{ChainID: chainID, Addr: addr, Home: tmkmsKMSHome, ProtocolVersion: protocol}
+	toml, err := cfg.Render()
+	if err != nil {
+		Fatal("Failed to render tmkms.toml: %v", err)
+	}
+
+	writeTmkmsFile("tmkms.toml", toml, 0644, tmkmsForce)
+	softsign := tmkms.SoftsignKey(ck)
+	writeTmkmsFile(tmkms.ConsensusKeyFile, softsign, 0600, tmkmsForce)
+	zeroBytes(softsign)
+	writeTmkmsIdentity()
+	writeTmkmsState(chainID)
+
+	// sekaid must listen where tmkms dials
+	if _, err := os.Stat(filepath.Join(tmkmsHome, "config", "config.toml")); err == nil && currentLaddr != laddr {
+		scall := config.ScallConfig{"config.priv_validator_laddr": laddr}
+		if err := scall.Apply(tmkmsHome); err != nil {
+			Fatal("Failed to set priv_validator_laddr: %v", err)
+		}
+		Log("Set config.priv_validator_laddr = %s", laddr)
+	}
+
+	fmt.Printf("chain_id: %s\naddr: %s\nprotocol_version: %s\nvalcons: %s\nout: %s\n",
+		chainID, addr, protocol, ck.ValconsAddress(), tmkmsOut)
+	Log("Once tmkms signs for %s, remove %s from the node", ck.HexAddress(), consensusKeyPath(tmkmsHome))
+}
+
+// checkSignerLaddr validates a priv_validator_laddr and refuses one that
+// accepts signer connections on a public address unless allowPublic
+func checkSignerLaddr(laddr string, allowPublic bool) {
+	public, err := node.PublicSignerAddrs(laddr)
+	if err != nil {
+		Fatal("%v", err)
+	}
+	if len(public) == 0 {
+		return
+	}
+	if !allowPublic {
+		Fatal("priv_validator_laddr %s listens on public address %s; bind a private interface such as the WireGuard IP, use a unix:// socket, or pass --allow-public-signer",
+			laddr, public[0])
+	}
+	Log("Warning: Remote signer port of %s is reachable on public address %s", laddr, public[0])
+}
+
+// signerDialAddr turns a listen address into the address tmkms dials
+func signerDialAddr(laddr string) (string, error) {
+	u, err := url.Parse(laddr)
+	if err == nil && u.Scheme == "unix" {
+		return laddr, nil
+	}
+	if err != nil || u.Scheme != "tcp" || u.Port() == "" {
+		return "", fmt.Errorf("invalid priv_validator_laddr %q, expected tcp://host:port or unix:///path", laddr)
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = defaultSignerHost
+	}
+	return "tcp://" + net.JoinHostPort(host, u.Port()), nil
+}
+
+// detectProtocolVersion asks sekaid for its CometBFT version. sekaid is
+// built with CometBFT 0.37, so v0.34 is assumed if that fails.
+func detectProtocolVersion() string {
+	out, err := exec.Command("/sekaid", "version", "--long").CombinedOutput()
+	if err == nil {
+		protocol, perr := tmkms.ProtocolVersion(string(out))
+		if perr == nil {
+			Log("Using protocol_version %s for sekaid's CometBFT", protocol)
+			return protocol
+		}
+		err = perr
+	}
+	Log("Warning: Could not detect sekaid's CometBFT version (%v); using protocol_version v0.34", err)
+	return "v0.34"
+}
+
+// tmkmsConsensusKey returns the key tmkms signs with: a new one, the key
+// already in --out, or the node's
+func tmkmsConsensusKey() *keys.ConsensusKey {
+	if tmkmsGenerateKey {
+		ck, err := keys.GenerateConsensusKey()
+		if err != nil {
+			Fatal("Failed to generate consensus key: %v", err)
+		}
+		Log("Generated consensus key %s", ck.ValconsAddress())
+		return ck
+	}
+	ck, err := keys.LoadConsensusKey(consensusKeyPath(tmkmsHome))
+	if err == nil {
+		return ck
+	}
+	if data, rerr := os.ReadFile(filepath.Join(tmkmsOut, tmkms.ConsensusKeyFile)); rerr == nil {
+		existing, perr := keys.ParseConsensusKey(data)
+		zeroBytes(data)
+		if perr == nil {
+			Log("Keeping consensus key %s in %s", existing.ValconsAddress(), tmkmsOut)
+			return existing
+		}
+	}
+	Fatal("Failed to load consensus key: %v (use --generate-key for a new one)", err)
+	return nil
+}
+
+// writeTmkmsFile writes name under --out, refusing to replace different
+// content unless force
+func writeTmkmsFile(name string, data []byte, perm os.FileMode, force bool) {
+	path := filepath.Join(tmkmsOut, name)
+	old, err := os.ReadFile(path)
+	if err == nil {
+		same := bytes.Equal(old, data)
+		zeroBytes(old)
+		if same {
+			Log("Unchanged %s", path)
+			return
+		}
+		if !force {
+			Fatal("%s already exists with different content; use --force to replace it", path)
+		}
+	}
+	dirMode := os.FileMode(0755)
+	if perm&0077 == 0 {
+		dirMode = 0700
+	}
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		Fatal("Failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := config.WriteFileAtomic(path, data, perm); err != nil {
+		Fatal("Failed to write %s: %v", path, err)
+	}
+	Log("Wrote %s", path)
+}
+
+// writeTmkmsIdentity creates the KMS identity key unless a valid one exists
+func writeTmkmsIdentity() {
+	path := filepath.Join(tmkmsOut, tmkms.IdentityKeyFile)
+	if data, err := os.ReadFile(path); err == nil {
+		k, perr := keys.ParseConsensusKey(data)
+		zeroBytes(data)
+		if perr != nil {
+			Fatal("%s is not a valid identity key: %v", path, perr)
+		}
+		k.Zero()
+		Log("Keeping %s", path)
+		return
+	}
+	k, err := keys.GenerateConsensusKey()
+	if err != nil {
+		Fatal("Failed to generate identity key: %v", err)
+	}
+	data := tmkms.SoftsignKey(k)
+	k.Zero()
+	writeTmkmsFile(tmkms.IdentityKeyFile, data, 0600, false)
+	zeroBytes(data)
+}
+
+// writeTmkmsState seeds the tmkms state from the node's signing state,
+// never lowering a state tmkms already has
+func writeTmkmsState(chainID string) {
+	s, err := node.LoadSignState(node.SignStatePath(tmkmsHome))
+	if err != nil {
+		Fatal("Failed to read signing state: %v", err)
+	}
+	path := filepath.Join(tmkmsOut, tmkms.StateFile(chainID))
+	if data, err := os.ReadFile(path); err == nil {
+		existing, perr := node.ParseSignState(data)
+		if perr != nil {
+			Fatal("%s: %v", path, perr)
+		}
+		if !existing.Less(s) {
+			Log("Keeping %s at %s", path, existing)
+			return
+		}
+	}
+	data, err := tmkms.State(s)
+	if err != nil {
+		Fatal("Failed to encode state: %v", err)
+	}
+	writeTmkmsFile(tmkms.StateFile(chainID), data, 0600, true)
+	Log("Seeded signing state at %s", s)
+}
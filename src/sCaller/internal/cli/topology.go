@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"scaller/internal/topology"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+)
+
+var topologyCmd = &cobra.Command{
+	Use:   "topology",
+	Short: "Work with sentry/validator topology files",
+}
+
+var topologyGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate scall.toml overrides for every node in a topology",
+	Long: `Reads a topology file and writes <name>.scall.toml for each node, with
+role settings and consistent seeds, persistent, private and unconditional
+peers. Pass the generated file to 'scaller join --config' on each machine.
+
+Topology format:
+  [[validator]]
+  name = "val-1"
+  id = "<node id>"
+  address = "10.200.0.1:26656"
+
+  [[sentry]]
+  name = "sentry-1"
+  id = "<node id>"
+  address = "203.0.113.10:26656"
+
+  [[seed]]   # optional
+  ...
+
+Example:
+  scaller topology generate --topology topology.toml --out ./configs`,
+	Run: runTopologyGenerate,
+}
+
+var (
+	topologyFile string
+	topologyOut  string
+)
+
+func init() {
+	topologyGenerateCmd.Flags().StringVar(&topologyFile, "topology", "", "Topology file (required)")
+	topologyGenerateCmd.Flags().StringVar(&topologyOut, "out", ".", "Output directory")
+	topologyGenerateCmd.MarkFlagRequired("topology")
+
+	topologyCmd.AddCommand(topologyGenerateCmd)
+}
+
+func runTopologyGenerate(cmd *cobra.Command, args []string) {
+	t, err := topology.Load(topologyFile)
+	if err != nil {
+		Fatal("%v", err)
+	}
+
+	configs, err := t.Generate()
+	if err != nil {
+		Fatal("Failed to generate configs: %v", err)
+	}
+
+	if err := os.MkdirAll(topologyOut, 0755); err != nil {
+		Fatal("Failed to create output directory: %v", err)
+	}
+
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(topologyOut, name+".scall.toml")
+		f, err := os.Create(path)
+		if err != nil {
+			Fatal("Failed to create %s: %v", path, err)
+		}
+		role, _ := t.RoleOf(name)
+		f.WriteString("# " + name + " (" + role + "), generated from " + filepath.Base(topologyFile) + "\n")
+		if err := toml.NewEncoder(f).Encode(configs[name]); err != nil {
+			f.Close()
+			Fatal("Failed to write %s: %v", path, err)
+		}
+		f.Close()
+		Log("Wrote %s", path)
+	}
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// BackupDirName is the directory under config/ that holds backups
+const BackupDirName = ".backups"
+
+// maxBackups is the number of backup sets kept per config directory
+const maxBackups = 20
+
+// backupSessions maps a config directory to the backup set of this process,
+// so every file changed by one scaller run lands in the same set and holds
+// its state from before that run
+var backupSessions = map[string]string{}
+
+// Backup is one set of config files saved before a scaller run changed them
+type Backup struct {
+	Timestamp string
+	Files     []string
+}
+
+// WriteFileAtomic writes data to a temp file in the same directory, syncs
+// it and renames it over path, so readers never see a partial file. The
+// mode of an existing file is preserved.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// writeConfigFile backs up the current file and atomically replaces it
+func writeConfigFile(path string, data []byte) error {
+	if err := backupFile(path); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	if err := WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// backupFile copies path into this run's backup set, once per run
+func backupFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	configDir := filepath.Dir(path)
+	session, err := backupSession(configDir)
+	if err != nil {
+		return err
+	}
+
+	dest := filepath.Join(configDir, BackupDirName, session, filepath.Base(path))
+	if _, err := os.Stat(dest); err == nil {
+		return nil
+	}
+	return WriteFileAtomic(dest, data, 0600)
+}
+
+// backupSession returns (creating on first use) the backup set for configDir
+func backupSession(configDir string) (string, error) {
+	if s, ok := backupSessions[configDir]; ok {
+		return s, nil
+	}
+
+	root := filepath.Join(configDir, BackupDirName)
+	base := time.Now().UTC().Format("20060102T150405Z")
+	name := base
+	for i := 2; ; i++ {
+		err := os.MkdirAll(root, 0700)
+		if err == nil {
+			err = os.Mkdir(filepath.Join(root, name), 0700)
+		}
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	backupSessions[configDir] = name
+
+	pruneBackups(configDir)
+	return name, nil
+}
+
+// ListBackups returns the backup sets of configDir, newest first
+func ListBackups(configDir string) ([]Backup, error) {
+	root := filepath.Join(configDir, BackupDirName)
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(root, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		b := Backup{Timestamp: e.Name()}
+		for _, f := range files {
+			b.Files = append(b.Files, f.Name())
+		}
+		if len(b.Files) > 0 {
+			backups = append(backups, b)
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Timestamp > backups[j].Timestamp })
+	return backups, nil
+}
+
+// Rollback restores the files of the backup set with the given timestamp
+// (latest if empty). The current files are backed up first, so a rollback
+// can itself be rolled back.
+func Rollback(configDir, timestamp string) (*Backup, error) {
+	backups, err := ListBackups(configDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no backups in %s", filepath.Join(configDir, BackupDirName))
+	}
+
+	var target *Backup
+	for i := range backups {
+		if timestamp == "" || backups[i].Timestamp == timestamp {
+			target = &backups[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("no backup with timestamp %s", timestamp)
+	}
+
+	for _, name := range target.Files {
+		data, err := os.ReadFile(filepath.Join(configDir, BackupDirName, target.Timestamp, name))
+		if err != nil {
+			return nil, err
+		}
+		if err := writeConfigFile(filepath.Join(configDir, name), data); err != nil {
+			return nil, err
+		}
+	}
+	return target, nil
+}
+
+// pruneBackups keeps the newest maxBackups sets
+func pruneBackups(configDir string) {
+	backups, err := ListBackups(configDir)
+	if err != nil || len(backups) <= maxBackups {
+		return
+	}
+	for _, b := range backups[maxBackups:] {
+		os.RemoveAll(filepath.Join(configDir, BackupDirName, b.Timestamp))
+	}
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"scaller/internal/node"
+)
+
+// DesiredFile records, under the scaller state dir, the values scaller last
+// applied, so later edits to the config files can be detected as drift
+const DesiredFile = "desired.json"
+
+// Desired is the recorded desired state of the config files. Files keeps the
+// registered prefixes of its keys, so later processes know their files.
+type Desired struct {
+	AppliedAt time.Time               `json:"applied_at"`
+	Files     map[string]string       `json:"files,omitempty"`
+	Values    map[string]DesiredValue `json:"values"`
+}
+
+// DesiredValue is one applied key. Values rendered from env or file
+// templates are kept only as the template and a hash of the result.
+type DesiredValue struct {
+	Value    string `json:"value,omitempty"`
+	Template string `json:"template,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+}
+
+// Drift is a key whose value in the config files differs from the desired one
+type Drift struct {
+	Key     string `json:"key"`
+	Want    string `json:"want"`
+	Got     string `json:"got,omitempty"`
+	Missing bool   `json:"missing,omitempty"`
+	Secret  bool   `json:"secret,omitempty"`
+}
+
+func desiredPath(home string) string {
+	return filepath.Join(node.StateDir(home), DesiredFile)
+}
+
+// LoadDesired reads the desired state of home; it is empty if none was recorded
+func LoadDesired(home string) (*Desired, error) {
+	d := &Desired{Values: map[string]DesiredValue{}}
+	data, err := os.ReadFile(desiredPath(home))
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", desiredPath(home), err)
+	}
+	if d.Values == nil {
+		d.Values = map[string]DesiredValue{}
+	}
+	for prefix, file := range d.Files {
+		if err := RegisterFile(prefix, file); err != nil {
+			return nil, fmt.Errorf("%s: %w", desiredPath(home), err)
+		}
+	}
+	return d, nil
+}
+
+// Save writes the desired state of home
+func (d *Desired) Save(home string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(node.StateDir(home), 0700); err != nil {
+		return err
+	}
+	return WriteFileAtomic(desiredPath(home), data, 0600)
+}
+
+// recordDesired merges applied overrides into the desired state. raw holds
+// the overrides as written, resolved the rendered values.
+func recordDesired(home string, raw, resolved ScallConfig) error {
+	d, err := LoadDesired(home)
+	if err != nil {
+		return err
+	}
+	for key := range resolved {
+		for prefix, file := range ConfigFiles {
+			if !builtinPrefixes[prefix] && strings.HasPrefix(key, prefix) {
+				if d.Files == nil {
+					d.Files = map[string]string{}
+				}
+				d.Files[prefix] = file
+			}
+		}
+	}
+	for key, value := range resolved {
+		// Arrays of tables are read back flattened, so record them that way
+		if items, ok := tableArray(value); ok && !IsSecret(raw[key]) {
+			for k := range d.Values {
+				if strings.HasPrefix(k, key+"[") {
+					delete(d.Values, k)
+				}
+			}
+			for i, item := range items {
+				for k, v := range Flatten(item) {
+					d.Values[fmt.Sprintf("%s[%d].%s", key, i, k)] = DesiredValue{Value: FormatValue(v)}
+				}
+			}
+			continue
+		}
+		if IsSecret(raw[key]) {
+			d.Values[key] = DesiredValue{Template: FormatValue(raw[key]), SHA256: hashValue(value)}
+		} else {
+			d.Values[key] = DesiredValue{Value: FormatValue(value)}
+		}
+	}
+	d.AppliedAt = time.Now().UTC()
+	return d.Save(home)
+}
+
+// forgetDesired drops key from the desired state, e.g. after unset
+func forgetDesired(home, key string) error {
+	d, err := LoadDesired(home)
+	if err != nil {
+		return err
+	}
+	if _, ok := d.Values[key]; !ok {
+		return nil
+	}
+	delete(d.Values, key)
+	return d.Save(home)
+}
+
+// Accept makes the current value of each key the desired one
+func (d *Desired) Accept(home string, keys []string) error {
+	current, err := LoadHome(home)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		value, ok := current[key]
+		if !ok {
+			delete(d.Values, key)
+			continue
+		}
+		if d.Values[key].Template != "" {
+			d.Values[key] = DesiredValue{Template: d.Values[key].Template, SHA256: hashValue(value)}
+		} else {
+			d.Values[key] = DesiredValue{Value: FormatValue(value)}
+		}
+	}
+	return d.Save(home)
+}
+
+// Check compares the config files under home with the desired state
+func (d *Desired) Check(home string) ([]Drift, error) {
+	current, err := LoadHome(home)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	for key, want := range d.Values {
+		got, ok := current[key]
+		switch {
+		case want.Template != "":
+			if ok && hashValue(got) == want.SHA256 {
+				continue
+			}
+			drifts = append(drifts, Drift{Key: key, Want: "(secret)", Got: "(secret)", Missing: !ok, Secret: true})
+		case !ok:
+			drifts = append(drifts, Drift{Key: key, Want: want.Value, Missing: true})
+		case FormatValue(got) != want.Value:
+			drifts = append(drifts, Drift{Key: key, Want: want.Value, Got: FormatValue(got)})
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].Key < drifts[j].Key })
+	return drifts, nil
+}
+
+// Overrides returns the desired values of keys as overrides to re-apply.
+// Secrets are returned as their templates and rendered again.
+func (d *Desired) Overrides(keys []string) ScallConfig {
+	s := make(ScallConfig, len(keys))
+	for _, key := range keys {
+		want, ok := d.Values[key]
+		if !ok {
+			continue
+		}
+		if want.Template != "" {
+			s[key] = ParseValue(want.Template)
+		} else {
+			s[key] = ParseValue(want.Value)
+		}
+	}
+	return s
+}
+
+// Enforce re-applies the desired value of every drifted key
+func (d *Desired) Enforce(home string) ([]Drift, error) {
+	drifts, err := d.Check(home)
+	if err != nil || len(drifts) == 0 {
+		return drifts, err
+	}
+	keys := make([]string, len(drifts))
+	for i, drift := range drifts {
+		keys[i] = drift.Key
+	}
+	if err := d.Overrides(keys).Apply(home); err != nil {
+		return drifts, err
+	}
+	return drifts, nil
+}
+
+func hashValue(v interface{}) string {
+	sum := sha256.Sum256([]byte(FormatValue(v)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// EnvPrefix starts environment variables that override scall keys:
+// SCALL_CONFIG__P2P__SEEDS sets config.p2p.seeds
+const EnvPrefix = "SCALL_"
+
+// Layer is one named source of overrides
+type Layer struct {
+	Name   string
+	Values ScallConfig
+}
+
+// Layers merges overrides from several sources; later layers win
+type Layers []Layer
+
+// Add appends a layer; empty layers are kept so they still show up as sources
+func (l *Layers) Add(name string, values ScallConfig) {
+	*l = append(*l, Layer{Name: name, Values: values})
+}
+
+// Merge returns the effective values
+func (l Layers) Merge() ScallConfig {
+	merged := make(ScallConfig)
+	for _, layer := range l {
+		for k, v := range layer.Values {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// Sources returns, for each effective key, the name of the layer that set it
+func (l Layers) Sources() map[string]string {
+	sources := make(map[string]string)
+	for _, layer := range l {
+		for k := range layer.Values {
+			sources[k] = layer.Name
+		}
+	}
+	return sources
+}
+
+// FromEnv collects SCALL_* variables from environ (os.Environ format).
+// "__" separates key segments; segments are lowercased, and "_" becomes "-"
+// when that matches a known key (SCALL_APP__PRUNING_KEEP_RECENT). Variables
+// that do not name a key are skipped with a warning, as the prefix may be
+// shared with unrelated settings.
+func FromEnv(environ []string) (values ScallConfig, warnings []string) {
+	values = make(ScallConfig)
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		key, err := envKey(strings.TrimPrefix(name, EnvPrefix))
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("ignoring %s: %v", name, err))
+			continue
+		}
+		values[key] = ParseValue(value)
+	}
+	return values, warnings
+}
+
+func envKey(name string) (string, error) {
+	parts := strings.Split(strings.ToLower(name), "__")
+	for _, p := range parts {
+		if p == "" {
+			return "", fmt.Errorf("empty key segment")
+		}
+	}
+	key := strings.Join(parts, ".")
+	if _, ok := schema[key]; !ok {
+		if dashed := strings.ReplaceAll(key, "_", "-"); schemaHas(dashed) {
+			return dashed, nil
+		}
+		// Only the key segments may be dashed; the namespace never is
+		for i := len(parts) - 1; i > 0; i-- {
+			try := make([]string, len(parts))
+			copy(try, parts)
+			try[i] = strings.ReplaceAll(try[i], "_", "-")
+			if schemaHas(strings.Join(try, ".")) {
+				return strings.Join(try, "."), nil
+			}
+		}
+	}
+	if _, _, err := SplitKey("", key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func schemaHas(key string) bool {
+	_, ok := schema[key]
+	return ok
+}
+
+// ParseSet parses --set style "key=value" assignments
+func ParseSet(assignments []string) (ScallConfig, error) {
+	result := make(ScallConfig)
+	for _, a := range assignments {
+		key, value, ok := strings.Cut(a, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set %q: expected key=value", a)
+		}
+		if _, _, err := SplitKey("", key); err != nil {
+			return nil, err
+		}
+		result[key] = ParseValue(value)
+	}
+	return result, nil
+}
+
+// ParseValue parses a string as a TOML literal (true, 100, ["a", "b"]),
+// falling back to the plain string
+func ParseValue(s string) interface{} {
+	var v map[string]interface{}
+	if _, err := toml.Decode("v = "+s, &v); err == nil {
+		return v["v"]
+	}
+	return s
+}
+
+// EnvLayer reads SCALL_* variables from the process environment
+func EnvLayer() (ScallConfig, []string) {
+	return FromEnv(os.Environ())
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -12,12 +15,106 @@ import (
 // Keys are dotted paths like "config.p2p.seeds" or "app.pruning"
 type ScallConfig map[string]interface{}
 
-// LoadScall loads scall.toml from path
+// ConfigFiles maps scall key prefixes to files relative to the sekaid home.
+// More files can be added with RegisterFile or a [files] table in scall.toml.
+var ConfigFiles = map[string]string{
+	"config.": "config/config.toml",
+	"app.":    "config/app.toml",
+	"client.": "config/client.toml",
+}
+
+// builtinPrefixes are the prefixes of ConfigFiles that need no registration
+var builtinPrefixes = map[string]bool{"config.": true, "app.": true, "client.": true}
+
+// FilesKey is the scall.toml table that registers extra prefixes:
+//
+//	[files]
+//	sekai = "config/sekai.toml"
+const FilesKey = "files"
+
+var prefixRe = regexp.MustCompile(`^[a-z0-9_-]+\.$`)
+
+// RegisterFile maps keys starting with prefix ("sekai" or "sekai.") to a
+// TOML file relative to the sekaid home
+func RegisterFile(prefix, file string) error {
+	if !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+	if !prefixRe.MatchString(prefix) {
+		return fmt.Errorf("invalid prefix %q: use lowercase letters, digits, '_' and '-'", prefix)
+	}
+	clean := filepath.Clean(file)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("file %q for prefix %s must be relative to the sekaid home", file, prefix)
+	}
+	if filepath.Ext(clean) != ".toml" {
+		return fmt.Errorf("file %q for prefix %s is not a .toml file", file, prefix)
+	}
+	if existing, ok := ConfigFiles[prefix]; ok && existing != clean {
+		return fmt.Errorf("prefix %s is already mapped to %s", prefix, existing)
+	}
+	ConfigFiles[prefix] = clean
+	return nil
+}
+
+// RegisterFiles registers and removes the [files] table of s
+func (s ScallConfig) RegisterFiles() error {
+	v, ok := s[FilesKey]
+	if !ok {
+		return nil
+	}
+	files, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be a table of prefix = \"file\"", FilesKey)
+	}
+	for prefix, f := range files {
+		file, ok := f.(string)
+		if !ok {
+			return fmt.Errorf("%s.%s must be a file path", FilesKey, prefix)
+		}
+		if err := RegisterFile(prefix, file); err != nil {
+			return err
+		}
+	}
+	delete(s, FilesKey)
+	return nil
+}
+
+// Prefixes returns the known key prefixes in sorted order
+func Prefixes() []string {
+	prefixes := make([]string, 0, len(ConfigFiles))
+	for p := range ConfigFiles {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// SplitKey splits "config.p2p.seeds" into its file path under home and
+// the key path within that file
+func SplitKey(home, key string) (string, []string, error) {
+	for _, prefix := range Prefixes() {
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			return filepath.Join(home, ConfigFiles[prefix]), strings.Split(strings.TrimPrefix(key, prefix), "."), nil
+		}
+	}
+	return "", nil, fmt.Errorf("key %q must start with one of: %s", key, strings.Join(Prefixes(), ", "))
+}
+
+// LoadScall loads scall.toml from path and registers its [files] table
 func LoadScall(path string) (ScallConfig, error) {
 	var cfg ScallConfig
 	if _, err := toml.DecodeFile(path, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to load scall.toml: %w", err)
 	}
+	if err := cfg.RegisterFiles(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for key := range cfg {
+		if _, _, err := SplitKey("", key); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
 	return cfg, nil
 }
 
@@ -31,74 +128,220 @@ func (s ScallConfig) ApplyToAppToml(appPath string) error {
 	return s.applyToFile(appPath, "app.")
 }
 
-func (s ScallConfig) applyToFile(filePath, prefix string) error {
-	// Load existing TOML as raw map
-	var existing map[string]interface{}
-	if _, err := toml.DecodeFile(filePath, &existing); err != nil {
-		return fmt.Errorf("failed to read %s: %w", filePath, err)
-	}
+// ApplyToClientToml applies client.* entries to client.toml
+func (s ScallConfig) ApplyToClientToml(clientPath string) error {
+	return s.applyToFile(clientPath, "client.")
+}
 
-	// Apply overrides
-	for key, value := range s {
-		if !strings.HasPrefix(key, prefix) {
+// Apply renders templates and applies every entry to its file under home.
+// Files without entries are left untouched; registered files that do not
+// exist yet are created. The applied values become the desired state.
+func (s ScallConfig) Apply(home string) error {
+	resolved, err := s.Resolve(&TemplateData{Home: home})
+	if err != nil {
+		return err
+	}
+	for _, prefix := range Prefixes() {
+		has := false
+		for key := range resolved {
+			if strings.HasPrefix(key, prefix) {
+				has = true
+				break
+			}
+		}
+		if !has {
 			continue
 		}
+		if err := resolved.applyToFile(filepath.Join(home, ConfigFiles[prefix]), prefix); err != nil {
+			return err
+		}
+	}
+	if err := recordDesired(home, s, resolved); err != nil {
+		return fmt.Errorf("failed to record desired config state: %w", err)
+	}
+	return nil
+}
 
-		// Remove prefix: "config.p2p.seeds" -> "p2p.seeds"
-		path := strings.TrimPrefix(key, prefix)
-		parts := strings.Split(path, ".")
+// LoadHome reads the config files under home into a flat ScallConfig.
+// Missing files are skipped.
+func LoadHome(home string) (ScallConfig, error) {
+	result := make(ScallConfig)
+	for prefix, file := range ConfigFiles {
+		path := filepath.Join(home, file)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		var m map[string]interface{}
+		if _, err := toml.DecodeFile(path, &m); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		for k, v := range Flatten(m) {
+			result[prefix+k] = v
+		}
+	}
+	return result, nil
+}
 
-		if err := setNestedValue(existing, parts, value); err != nil {
-			return fmt.Errorf("failed to set %s: %w", key, err)
+// Flatten turns nested tables into dotted keys. Arrays of tables are
+// flattened with an index suffix, e.g. "chain[0].id".
+func Flatten(m map[string]interface{}) ScallConfig {
+	result := make(ScallConfig)
+	for k, v := range m {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			for nk, nv := range Flatten(x) {
+				result[k+"."+nk] = nv
+			}
+		case []map[string]interface{}:
+			for i, item := range x {
+				for nk, nv := range Flatten(item) {
+					result[fmt.Sprintf("%s[%d].%s", k, i, nk)] = nv
+				}
+			}
+		default:
+			result[k] = v
 		}
 	}
+	return result
+}
 
-	// Write back
-	f, err := os.Create(filePath)
+// GetValue reads a single key from the config files under home
+func GetValue(home, key string) (interface{}, bool, error) {
+	path, parts, err := SplitKey(home, key)
 	if err != nil {
-		return fmt.Errorf("failed to create %s: %w", filePath, err)
+		return nil, false, err
 	}
-	defer f.Close()
+	doc, err := loadDocument(path)
+	if err != nil {
+		return nil, false, err
+	}
+	v, ok := doc.Get(parts)
+	return v, ok, nil
+}
 
-	encoder := toml.NewEncoder(f)
-	if err := encoder.Encode(existing); err != nil {
-		return fmt.Errorf("failed to encode %s: %w", filePath, err)
+// UnsetValue removes a key from its config file so sekaid falls back to its default
+func UnsetValue(home, key string) error {
+	path, parts, err := SplitKey(home, key)
+	if err != nil {
+		return err
+	}
+	doc, err := loadDocument(path)
+	if err != nil {
+		return err
 	}
+	if err := doc.Unset(parts); err != nil {
+		return fmt.Errorf("failed to unset %s: %w", key, err)
+	}
+	if err := writeConfigFile(path, []byte(doc.String())); err != nil {
+		return err
+	}
+	return forgetDesired(home, key)
+}
 
-	return nil
+// FormatValue formats a value the way it appears in a TOML file
+func FormatValue(v interface{}) string {
+	s, err := encodeTOMLValue(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return s
 }
 
-// setNestedValue sets a value in a nested map using a path like ["p2p", "seeds"]
-func setNestedValue(m map[string]interface{}, path []string, value interface{}) error {
-	if len(path) == 0 {
-		return fmt.Errorf("empty path")
+func loadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	doc, err := ParseDocument(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
 	}
+	return doc, nil
+}
 
-	if len(path) == 1 {
-		m[path[0]] = value
-		return nil
+// applyToFile patches prefixed entries into filePath in place, keeping
+// comments, key order and formatting of the original file
+func (s ScallConfig) applyToFile(filePath, prefix string) error {
+	var doc *Document
+	var err error
+	if _, statErr := os.Stat(filePath); os.IsNotExist(statErr) && !builtinPrefix(prefix) {
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		doc, _ = ParseDocument("")
+	} else if doc, err = loadDocument(filePath); err != nil {
+		return err
+	}
+	original := doc.String()
+
+	// Apply overrides in key order so repeated runs produce identical files
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
 	}
+	sort.Strings(keys)
 
-	// Navigate/create nested maps
-	key := path[0]
-	rest := path[1:]
+	for _, key := range keys {
+		// Remove prefix: "config.p2p.seeds" -> "p2p.seeds"
+		path := strings.TrimPrefix(key, prefix)
+		parts := strings.Split(path, ".")
 
-	nested, exists := m[key]
-	if !exists {
-		// Create new nested map
-		nested = make(map[string]interface{})
-		m[key] = nested
+		if err := doc.Set(parts, s[key]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
 	}
 
-	nestedMap, ok := nested.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("cannot set nested value: %s is not a map", key)
+	// Write back (backup + atomic replace), skipping files that did not change
+	if doc.String() == original {
+		return nil
 	}
+	return writeConfigFile(filePath, []byte(doc.String()))
+}
 
-	return setNestedValue(nestedMap, rest, value)
+// builtinPrefix reports whether prefix maps to a file created by sekaid init
+func builtinPrefix(prefix string) bool {
+	return prefix == "config." || prefix == "app." || prefix == "client."
 }
 
 // SetValue sets a single value in the scall config
 func (s ScallConfig) SetValue(key string, value interface{}) {
 	s[key] = value
 }
+
+// Change describes the effect of one override on its target file. Secret
+// is set when the new value comes from an env or file template.
+type Change struct {
+	Key    string
+	Old    interface{}
+	New    interface{}
+	Exists bool
+	Secret bool
+}
+
+// Diff reports the overrides that would change the config files under
+// home, sorted by key. Missing files are treated as empty. Templates that
+// cannot be rendered yet (e.g. .NodeID before init) are compared as written.
+func (s ScallConfig) Diff(home string) ([]Change, error) {
+	current, err := LoadHome(home)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &TemplateData{Home: home}
+	var changes []Change
+	for key, value := range s {
+		if resolved, err := (ScallConfig{key: value}).Resolve(data); err == nil {
+			value = resolved[key]
+		}
+		old, ok := current[key]
+		if ok && FormatValue(old) == FormatValue(value) {
+			continue
+		}
+		changes = append(changes, Change{Key: key, Old: old, New: value, Exists: ok, Secret: IsSecret(s[key])})
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
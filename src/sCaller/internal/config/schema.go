@@ -0,0 +1,479 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the expected type of a config value
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindBool
+	KindInt
+	KindFloat
+	// KindUintString is a non-negative integer stored as a TOML string,
+	// as app.toml does for the pruning settings
+	KindUintString
+	// KindDuration is a Go duration string such as "10s"
+	KindDuration
+	KindStringArray
+	// KindAny accepts any value without checks
+	KindAny
+)
+
+func (k Kind) String() string {
+	return [...]string{"string", "bool", "integer", "float", "integer string", "duration", "string array", "any"}[k]
+}
+
+// KeySpec describes one known key of config.toml or app.toml
+type KeySpec struct {
+	Kind       Kind
+	Enum       []string
+	Min, Max   int64
+	HasRange   bool
+	Deprecated string
+}
+
+func str() KeySpec                  { return KeySpec{Kind: KindString} }
+func boolean() KeySpec              { return KeySpec{Kind: KindBool} }
+func uintStr() KeySpec              { return KeySpec{Kind: KindUintString} }
+func duration() KeySpec             { return KeySpec{Kind: KindDuration} }
+func strArray() KeySpec             { return KeySpec{Kind: KindStringArray} }
+func enum(values ...string) KeySpec { return KeySpec{Kind: KindString, Enum: values} }
+func integer(min, max int64) KeySpec {
+	return KeySpec{Kind: KindInt, Min: min, Max: max, HasRange: true}
+}
+func deprecated(k KeySpec, msg string) KeySpec {
+	k.Deprecated = msg
+	return k
+}
+
+const maxInt = math.MaxInt64
+
+// schema lists the keys sekaid understands in config.toml (CometBFT),
+// app.toml and client.toml (Cosmos SDK), keyed by their scall name
+var schema = map[string]KeySpec{
+	// config.toml: base
+	"config.proxy_app":                 str(),
+	"config.moniker":                   str(),
+	"config.fast_sync":                 deprecated(boolean(), "use config.block_sync"),
+	"config.block_sync":                boolean(),
+	"config.db_backend":                enum("goleveldb", "cleveldb", "boltdb", "rocksdb", "badgerdb", "pebbledb"),
+	"config.db_dir":                    str(),
+	"config.log_level":                 str(),
+	"config.log_format":                enum("plain", "json"),
+	"config.genesis_file":              str(),
+	"config.priv_validator_key_file":   str(),
+	"config.priv_validator_state_file": str(),
+	"config.priv_validator_laddr":      str(),
+	"config.node_key_file":             str(),
+	"config.abci":                      enum("socket", "grpc"),
+	"config.filter_peers":              boolean(),
+
+	// config.toml: rpc
+	"config.rpc.laddr":                                    str(),
+	"config.rpc.cors_allowed_origins":                     strArray(),
+	"config.rpc.cors_allowed_methods":                     strArray(),
+	"config.rpc.cors_allowed_headers":                     strArray(),
+	"config.rpc.grpc_laddr":                               str(),
+	"config.rpc.grpc_max_open_connections":                integer(0, maxInt),
+	"config.rpc.unsafe":                                   boolean(),
+	"config.rpc.max_open_connections":                     integer(0, maxInt),
+	"config.rpc.max_subscription_clients":                 integer(0, maxInt),
+	"config.rpc.max_subscriptions_per_client":             integer(0, maxInt),
+	"config.rpc.experimental_subscription_buffer_size":    integer(100, maxInt),
+	"config.rpc.experimental_websocket_write_buffer_size": integer(0, maxInt),
+	"config.rpc.experimental_close_on_slow_client":        boolean(),
+	"config.rpc.timeout_broadcast_tx_commit":              duration(),
+	"config.rpc.max_body_bytes":                           integer(1, maxInt),
+	"config.rpc.max_header_bytes":                         integer(1, maxInt),
+	"config.rpc.tls_cert_file":                            str(),
+	"config.rpc.tls_key_file":                             str(),
+	"config.rpc.pprof_laddr":                              str(),
+
+	// config.toml: p2p
+	"config.p2p.laddr":                            str(),
+	"config.p2p.external_address":                 str(),
+	"config.p2p.seeds":                            str(),
+	"config.p2p.persistent_peers":                 str(),
+	"config.p2p.upnp":                             deprecated(boolean(), "UPnP support was removed from CometBFT"),
+	"config.p2p.addr_book_file":                   str(),
+	"config.p2p.addr_book_strict":                 boolean(),
+	"config.p2p.max_num_inbound_peers":            integer(0, maxInt),
+	"config.p2p.max_num_outbound_peers":           integer(0, maxInt),
+	"config.p2p.unconditional_peer_ids":           str(),
+	"config.p2p.persistent_peers_max_dial_period": duration(),
+	"config.p2p.flush_throttle_timeout":           duration(),
+	"config.p2p.max_packet_msg_payload_size":      integer(1, maxInt),
+	"config.p2p.send_rate":                        integer(1, maxInt),
+	"config.p2p.recv_rate":                        integer(1, maxInt),
+	"config.p2p.pex":                              boolean(),
+	"config.p2p.seed_mode":                        boolean(),
+	"config.p2p.private_peer_ids":                 str(),
+	"config.p2p.allow_duplicate_ip":               boolean(),
+	"config.p2p.handshake_timeout":                duration(),
+	"config.p2p.dial_timeout":                     duration(),
+
+	// config.toml: mempool
+	"config.mempool.version":                   enum("v0", "v1"),
+	"config.mempool.recheck":                   boolean(),
+	"config.mempool.broadcast":                 boolean(),
+	"config.mempool.wal_dir":                   str(),
+	"config.mempool.size":                      integer(1, maxInt),
+	"config.mempool.max_txs_bytes":             integer(1, maxInt),
+	"config.mempool.cache_size":                integer(0, maxInt),
+	"config.mempool.keep-invalid-txs-in-cache": boolean(),
+	"config.mempool.max_tx_bytes":              integer(1, maxInt),
+	"config.mempool.max_batch_bytes":           deprecated(integer(0, maxInt), "batching was removed from the mempool reactor"),
+	"config.mempool.ttl-duration":              duration(),
+	"config.mempool.ttl-num-blocks":            integer(0, maxInt),
+
+	// config.toml: statesync
+	"config.statesync.enable":                boolean(),
+	"config.statesync.rpc_servers":           str(),
+	"config.statesync.trust_height":          integer(0, maxInt),
+	"config.statesync.trust_hash":            str(),
+	"config.statesync.trust_period":          duration(),
+	"config.statesync.discovery_time":        duration(),
+	"config.statesync.temp_dir":              str(),
+	"config.statesync.chunk_request_timeout": duration(),
+	"config.statesync.chunk_fetchers":        integer(1, maxInt),
+
+	// config.toml: block sync
+	"config.fastsync.version":  deprecated(enum("v0", "v1", "v2"), "use config.blocksync.version"),
+	"config.blocksync.version": enum("v0"),
+
+	// config.toml: consensus
+	"config.consensus.wal_file":                        str(),
+	"config.consensus.timeout_propose":                 duration(),
+	"config.consensus.timeout_propose_delta":           duration(),
+	"config.consensus.timeout_prevote":                 duration(),
+	"config.consensus.timeout_prevote_delta":           duration(),
+	"config.consensus.timeout_precommit":               duration(),
+	"config.consensus.timeout_precommit_delta":         duration(),
+	"config.consensus.timeout_commit":                  duration(),
+	"config.consensus.double_sign_check_height":        integer(0, maxInt),
+	"config.consensus.skip_timeout_commit":             boolean(),
+	"config.consensus.create_empty_blocks":             boolean(),
+	"config.consensus.create_empty_blocks_interval":    duration(),
+	"config.consensus.peer_gossip_sleep_duration":      duration(),
+	"config.consensus.peer_query_maj23_sleep_duration": duration(),
+
+	// config.toml: storage, indexer, instrumentation
+	"config.storage.discard_abci_responses":         boolean(),
+	"config.tx_index.indexer":                       enum("kv", "null", "psql"),
+	"config.tx_index.psql-conn":                     str(),
+	"config.instrumentation.prometheus":             boolean(),
+	"config.instrumentation.prometheus_listen_addr": str(),
+	"config.instrumentation.max_open_connections":   integer(0, maxInt),
+	"config.instrumentation.namespace":              str(),
+
+	// app.toml: base
+	"app.minimum-gas-prices":    str(),
+	"app.pruning":               enum("default", "nothing", "everything", "custom"),
+	"app.pruning-keep-recent":   uintStr(),
+	"app.pruning-keep-every":    deprecated(uintStr(), "removed in Cosmos SDK v0.46, use app.state-sync.snapshot-interval"),
+	"app.pruning-interval":      uintStr(),
+	"app.halt-height":           integer(0, maxInt),
+	"app.halt-time":             integer(0, maxInt),
+	"app.min-retain-blocks":     integer(0, maxInt),
+	"app.inter-block-cache":     boolean(),
+	"app.index-events":          strArray(),
+	"app.iavl-cache-size":       integer(0, maxInt),
+	"app.iavl-disable-fastnode": boolean(),
+	"app.iavl-lazy-loading":     boolean(),
+	"app.app-db-backend":        enum("", "goleveldb", "cleveldb", "boltdb", "rocksdb", "badgerdb", "pebbledb"),
+
+	// app.toml: telemetry
+	"app.telemetry.service-name":              str(),
+	"app.telemetry.enabled":                   boolean(),
+	"app.telemetry.enable-hostname":           boolean(),
+	"app.telemetry.enable-hostname-label":     boolean(),
+	"app.telemetry.enable-service-label":      boolean(),
+	"app.telemetry.prometheus-retention-time": integer(0, maxInt),
+	"app.telemetry.global-labels":             KeySpec{Kind: KindAny},
+
+	// app.toml: api, grpc
+	"app.api.enable":                  boolean(),
+	"app.api.swagger":                 boolean(),
+	"app.api.address":                 str(),
+	"app.api.max-open-connections":    integer(0, maxInt),
+	"app.api.rpc-read-timeout":        integer(0, maxInt),
+	"app.api.rpc-write-timeout":       integer(0, maxInt),
+	"app.api.rpc-max-body-bytes":      integer(0, maxInt),
+	"app.api.enabled-unsafe-cors":     boolean(),
+	"app.grpc.enable":                 boolean(),
+	"app.grpc.address":                str(),
+	"app.grpc-web.enable":             boolean(),
+	"app.grpc-web.address":            str(),
+	"app.grpc-web.enable-unsafe-cors": boolean(),
+
+	// client.toml
+	"client.chain-id":        str(),
+	"client.keyring-backend": enum("os", "file", "kwallet", "pass", "test", "memory"),
+	"client.output":          enum("text", "json"),
+	"client.node":            str(),
+	"client.broadcast-mode":  enum("sync", "async", "block"),
+
+	// app.toml: state sync snapshots
+	"app.state-sync.snapshot-interval":    integer(0, maxInt),
+	"app.state-sync.snapshot-keep-recent": integer(0, maxInt),
+}
+
+// LookupSpec returns the schema entry for a scall key
+func LookupSpec(key string) (KeySpec, bool) {
+	spec, ok := schema[key]
+	return spec, ok
+}
+
+// Validate checks every config.*, app.* and client.* entry against the schema and
+// coerces values to the expected type where that is lossless. Unknown keys
+// produce warnings, or errors in strict mode. All problems are reported at once.
+func (s ScallConfig) Validate(strict bool) ([]string, error) {
+	var warnings, problems []string
+
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !schemaNamespace(key) {
+			continue
+		}
+
+		if isSchemaTable(key) {
+			problems = append(problems, fmt.Sprintf("%s: is a table and cannot be set to a value", key))
+			continue
+		}
+
+		spec, ok := schema[key]
+		if !ok {
+			msg := fmt.Sprintf("%s: unknown key", key)
+			if suggestion := suggestKey(key); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean %s?)", suggestion)
+			}
+			if strict {
+				problems = append(problems, msg)
+			} else {
+				warnings = append(warnings, msg)
+			}
+			continue
+		}
+
+		if spec.Deprecated != "" {
+			warnings = append(warnings, fmt.Sprintf("%s: deprecated, %s", key, spec.Deprecated))
+		}
+
+		// Templates are checked once rendered at apply time
+		if hasTemplate(s[key]) {
+			continue
+		}
+
+		value, err := coerce(spec, s[key])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		s[key] = value
+	}
+
+	if len(problems) > 0 {
+		return warnings, fmt.Errorf("invalid config overrides:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return warnings, nil
+}
+
+// coerce converts value to the kind in spec and checks enum and range
+func coerce(spec KeySpec, value interface{}) (interface{}, error) {
+	switch spec.Kind {
+	case KindString:
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case int64, int, bool:
+			s = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		if len(spec.Enum) > 0 && !contains(spec.Enum, s) {
+			return nil, fmt.Errorf("invalid value %q (allowed: %s)", s, strings.Join(spec.Enum, ", "))
+		}
+		return s, nil
+
+	case KindBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("expected bool, got %q", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected bool, got %T", value)
+
+	case KindInt, KindUintString:
+		n, err := toInt(value)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Kind == KindUintString {
+			if n < 0 {
+				return nil, fmt.Errorf("must not be negative, got %d", n)
+			}
+			return strconv.FormatInt(n, 10), nil
+		}
+		if spec.HasRange && (n < spec.Min || n > spec.Max) {
+			if spec.Max == maxInt {
+				return nil, fmt.Errorf("must be at least %d, got %d", spec.Min, n)
+			}
+			return nil, fmt.Errorf("must be between %d and %d, got %d", spec.Min, spec.Max, n)
+		}
+		return n, nil
+
+	case KindFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("expected float, got %q", v)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("expected float, got %T", value)
+
+	case KindDuration:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected duration string like \"10s\", got %T", value)
+		}
+		if _, err := time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("invalid duration %q", s)
+		}
+		return s, nil
+
+	case KindStringArray:
+		switch v := value.(type) {
+		case []string:
+			return v, nil
+		case []interface{}:
+			out := make([]string, len(v))
+			for i, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected array of strings, element %d is %T", i, item)
+				}
+				out[i] = s
+			}
+			return out, nil
+		case string:
+			// Comma-separated lists are split for convenience
+			if v == "" {
+				return []string{}, nil
+			}
+			parts := strings.Split(v, ",")
+			for i := range parts {
+				parts[i] = strings.TrimSpace(parts[i])
+			}
+			return parts, nil
+		}
+		return nil, fmt.Errorf("expected array of strings, got %T", value)
+	}
+	return value, nil
+}
+
+func toInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("expected integer, got %v", v)
+		}
+		return int64(v), nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected integer, got %q", v)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("expected integer, got %T", value)
+}
+
+// schemaNamespace reports whether key belongs to a file covered by the schema
+func schemaNamespace(key string) bool {
+	for _, prefix := range []string{"config.", "app.", "client."} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSchemaTable reports whether key names a section such as config.p2p
+func isSchemaTable(key string) bool {
+	prefix := key + "."
+	for k := range schema {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestKey returns the closest known key within a small edit distance
+func suggestKey(key string) string {
+	best, bestDist := "", 4
+	for k := range schema {
+		if d := levenshtein(key, k); d < bestDist || (d == bestDist && k < best) {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
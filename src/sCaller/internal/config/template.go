@@ -0,0 +1,248 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"scaller/internal/node"
+)
+
+// Values containing "{{" are Go templates rendered when overrides are
+// applied, so one scall.toml can be shared by a fleet:
+//
+//	"config.p2p.external_address" = "tcp://{{ .ExternalIP }}:26656"
+//	"config.moniker" = "{{ .Hostname }}"
+//	"app.api.address" = "tcp://{{ env \"API_HOST\" }}:1317"
+//	"config.statesync.trust_hash" = "{{ file \"/run/secrets/trust_hash\" }}"
+//
+// Fields: .Home, .NodeID, .Moniker, .ChainID, .Hostname, .ExternalIP.
+// Functions: env (fails if unset), file (contents without trailing newline).
+//
+// .ExternalIP asks third-party services and is off unless
+// $SCALLER_EXTERNAL_IP_SERVICES lists them.
+
+// ExternalIPServicesEnv lists, comma separated, the URLs .ExternalIP asks in
+// order for this host's public address; "default" selects
+// defaultExternalIPServices
+const ExternalIPServicesEnv = "SCALLER_EXTERNAL_IP_SERVICES"
+
+// defaultExternalIPServices are the services "default" selects
+var defaultExternalIPServices = []string{
+	"https://api.ipify.org",
+	"https://ifconfig.me/ip",
+	"https://icanhazip.com",
+}
+
+// Logf, if set, reports requests templates make to other hosts
+var Logf func(format string, args ...interface{})
+
+var secretTemplateRe = regexp.MustCompile(`{{[^}]*\b(env|file)\b`)
+
+// TemplateData is the template context for one sekaid home. Fields are
+// looked up only when a template uses them.
+type TemplateData struct {
+	Home string
+
+	externalIP string
+}
+
+// NodeID returns the P2P node ID from node_key.json
+func (d *TemplateData) NodeID() (string, error) {
+	return node.ID(d.Home)
+}
+
+// Moniker returns the moniker from config.toml
+func (d *TemplateData) Moniker() (string, error) {
+	v, ok, err := GetValue(d.Home, "config.moniker")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("moniker is not set in config.toml")
+	}
+	return fmt.Sprint(v), nil
+}
+
+// ChainID returns the chain ID from genesis.json
+func (d *TemplateData) ChainID() (string, error) {
+	data, err := os.ReadFile(filepath.Join(d.Home, "config", "genesis.json"))
+	if err != nil {
+		return "", err
+	}
+	var g struct {
+		ChainID string `json:"chain_id"`
+	}
+	if err := json.Unmarshal(data, &g); err != nil || g.ChainID == "" {
+		return "", fmt.Errorf("no chain_id in genesis.json")
+	}
+	return g.ChainID, nil
+}
+
+// Hostname returns the host name
+func (d *TemplateData) Hostname() (string, error) {
+	return os.Hostname()
+}
+
+// ExternalIP detects the public IP address of this host with the services
+// of $SCALLER_EXTERNAL_IP_SERVICES
+func (d *TemplateData) ExternalIP() (string, error) {
+	if d.externalIP != "" {
+		return d.externalIP, nil
+	}
+
+	services := externalIPServices()
+	if len(services) == 0 {
+		return "", fmt.Errorf("external IP lookup is off; set %s to \"default\" or a list of URLs, or use {{ env \"EXTERNAL_IP\" }}", ExternalIPServicesEnv)
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	for _, url := range services {
+		if Logf != nil {
+			Logf("Asking %s for the external IP (%s)", url, ExternalIPServicesEnv)
+		}
+		resp, err := client.Get(url)
+		if err != nil {
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue
+		}
+		if ip := net.ParseIP(strings.TrimSpace(string(body))); ip != nil {
+			d.externalIP = ip.String()
+			return d.externalIP, nil
+		}
+	}
+	return "", fmt.Errorf("failed to detect external IP")
+}
+
+// externalIPServices returns the URLs set in $SCALLER_EXTERNAL_IP_SERVICES
+func externalIPServices() []string {
+	var services []string
+	for _, s := range strings.Split(os.Getenv(ExternalIPServicesEnv), ",") {
+		switch s = strings.TrimSpace(s); s {
+		case "":
+		case "default":
+			services = append(services, defaultExternalIPServices...)
+		default:
+			services = append(services, s)
+		}
+	}
+	return services
+}
+
+var templateFuncs = template.FuncMap{
+	"env": func(name string) (string, error) {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	},
+	"file": func(path string) (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	},
+}
+
+// IsTemplate reports whether v is a string holding a template
+func IsTemplate(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && strings.Contains(s, "{{")
+}
+
+// IsSecret reports whether v reads from the environment or a file, so its
+// rendered value should not be printed
+func IsSecret(v interface{}) bool {
+	if items, ok := v.([]interface{}); ok {
+		for _, item := range items {
+			if IsSecret(item) {
+				return true
+			}
+		}
+		return false
+	}
+	s, ok := v.(string)
+	return ok && secretTemplateRe.MatchString(s)
+}
+
+// Resolve returns a copy of s with templates rendered against data. Rendered
+// values of schema keys are coerced to their kind; other keys are copied as is.
+func (s ScallConfig) Resolve(data *TemplateData) (ScallConfig, error) {
+	result := make(ScallConfig, len(s))
+	for key, value := range s {
+		if !hasTemplate(value) {
+			result[key] = value
+			continue
+		}
+		v, err := renderValue(key, value, data)
+		if err != nil {
+			return nil, err
+		}
+		if spec, ok := schema[key]; ok {
+			if v, err = coerce(spec, v); err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+		}
+		result[key] = v
+	}
+	return result, nil
+}
+
+func hasTemplate(value interface{}) bool {
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			if hasTemplate(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return IsTemplate(value)
+}
+
+// renderValue renders a template string, or every template in an array
+func renderValue(key string, value interface{}, data *TemplateData) (interface{}, error) {
+	switch x := value.(type) {
+	case string:
+		if !IsTemplate(x) {
+			return x, nil
+		}
+		return renderTemplate(key, x, data)
+	case []interface{}:
+		items := make([]interface{}, len(x))
+		for i, item := range x {
+			v, err := renderValue(key, item, data)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = v
+		}
+		return items, nil
+	}
+	return value, nil
+}
+
+func renderTemplate(key, text string, data *TemplateData) (string, error) {
+	t, err := template.New(key).Option("missingkey=error").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: invalid template: %w", key, err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return b.String(), nil
+}
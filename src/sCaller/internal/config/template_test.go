@@ -0,0 +1,31 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExternalIPIsOptIn(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("203.0.113.7\n"))
+	}))
+	defer srv.Close()
+	tmpl := ScallConfig{"config.p2p.external_address": "tcp://{{ .ExternalIP }}:26656"}
+
+	t.Setenv(ExternalIPServicesEnv, "")
+	if _, err := tmpl.Resolve(&TemplateData{}); err == nil || requests != 0 {
+		t.Fatalf("lookup without %s: error %v after %d requests", ExternalIPServicesEnv, err, requests)
+	}
+
+	t.Setenv(ExternalIPServicesEnv, "http://127.0.0.1:1, "+srv.URL)
+	got, err := tmpl.Resolve(&TemplateData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "tcp://203.0.113.7:26656"; got["config.p2p.external_address"] != want {
+		t.Errorf("external_address %v, want %s", got["config.p2p.external_address"], want)
+	}
+}
@@ -0,0 +1,729 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Document is a TOML file edited in place: values are replaced or inserted
+// in the original text so comments, key order and layout are preserved.
+//
+// Keys of array-of-tables elements are addressed with an index suffix on the
+// table name, e.g. ["chain[0]", "id"] for the first [[chain]] entry. Setting
+// an index one past the last element appends a new element, and setting the
+// array itself to a list of tables rewrites all of its [[chain]] entries.
+type Document struct {
+	text    string
+	tables  []*docTable
+	entries []*docEntry
+}
+
+// docTable is a table header ([a.b] or [[a.b]]) or the implicit root table
+type docTable struct {
+	path    []string
+	start   int // offset of the header line
+	isArray bool
+}
+
+// docEntry is a key/value line; path is the full path from the root
+type docEntry struct {
+	path       []string
+	table      *docTable
+	keyStart   int
+	valueStart int
+	valueEnd   int
+	lineEnd    int
+}
+
+var (
+	bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	indexRe   = regexp.MustCompile(`^(.+)\[(\d+)\]$`)
+)
+
+// ParseDocument parses TOML text for in-place editing
+func ParseDocument(text string) (*Document, error) {
+	if _, err := toml.Decode(text, new(map[string]interface{})); err != nil {
+		return nil, err
+	}
+	d := &Document{text: text}
+	if err := d.parse(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// String returns the edited TOML text
+func (d *Document) String() string {
+	return d.text
+}
+
+// Set sets the value at path, replacing the existing value text or inserting
+// a new key. Missing tables are created.
+func (d *Document) Set(path []string, value interface{}) error {
+	if len(path) == 0 {
+		return fmt.Errorf("empty path")
+	}
+	encoded, err := encodeTOMLValue(value)
+	if err != nil {
+		return fmt.Errorf("cannot encode %s: %w", strings.Join(path, "."), err)
+	}
+
+	if e := d.entry(path); e != nil {
+		return d.replace(e.valueStart, e.valueEnd, encoded)
+	}
+	if items, ok := tableArray(value); ok {
+		return d.setArray(path, items)
+	}
+	if d.isTable(path) {
+		return fmt.Errorf("cannot set %s: it is a table", strings.Join(path, "."))
+	}
+	for i := 1; i < len(path); i++ {
+		if e := d.entry(path[:i]); e != nil {
+			return fmt.Errorf("cannot set nested value: %s is not a table", strings.Join(path[:i], "."))
+		}
+	}
+
+	parent := path[:len(path)-1]
+	if err := d.ensureElements(parent); err != nil {
+		return err
+	}
+
+	// Parent table has a header: append the key to it
+	if t := d.table(parent); t != nil {
+		return d.insertIn(t, path[len(path)-1:], encoded)
+	}
+
+	// Parent exists implicitly through dotted keys: add another dotted key
+	// next to the last one that defines it
+	var last *docEntry
+	for _, e := range d.entries {
+		if hasPrefix(e.path, parent) && (last == nil || e.lineEnd > last.lineEnd) {
+			last = e
+		}
+	}
+	if last != nil {
+		return d.insertAt(last.lineEnd, path[len(last.table.path):], encoded)
+	}
+
+	if len(parent) == 0 {
+		return d.insertIn(d.tables[0], path, encoded)
+	}
+	return d.appendTable(parent, path[len(path)-1], encoded)
+}
+
+// Get returns the decoded value at path
+func (d *Document) Get(path []string) (interface{}, bool) {
+	e := d.entry(path)
+	if e == nil {
+		return nil, false
+	}
+	var v map[string]interface{}
+	if _, err := toml.Decode("v = "+d.text[e.valueStart:e.valueEnd], &v); err != nil {
+		return nil, false
+	}
+	return v["v"], true
+}
+
+// Unset removes the key at path together with its line
+func (d *Document) Unset(path []string) error {
+	e := d.entry(path)
+	if e == nil {
+		if d.isTable(path) {
+			return fmt.Errorf("%s is a table", strings.Join(path, "."))
+		}
+		return fmt.Errorf("%s is not set", strings.Join(path, "."))
+	}
+	return d.replace(e.keyStart, e.lineEnd, "")
+}
+
+func (d *Document) entry(path []string) *docEntry {
+	for _, e := range d.entries {
+		if equalPath(e.path, path) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (d *Document) table(path []string) *docTable {
+	for _, t := range d.tables {
+		if equalPath(t.path, path) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (d *Document) isTable(path []string) bool {
+	if d.table(path) != nil {
+		return true
+	}
+	for _, t := range d.tables {
+		if len(t.path) > len(path) && hasPrefix(t.path, path) {
+			return true
+		}
+	}
+	for _, e := range d.entries {
+		if len(e.path) > len(path) && hasPrefix(e.path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// insertIn adds key after the last entry of t, or directly below its header
+func (d *Document) insertIn(t *docTable, key []string, encoded string) error {
+	pos := 0
+	if t.path != nil {
+		pos = lineEnd(d.text, t.start)
+	}
+	for _, e := range d.entries {
+		if e.table == t && e.lineEnd > pos {
+			pos = e.lineEnd
+		}
+	}
+	return d.insertAt(pos, key, encoded)
+}
+
+func (d *Document) insertAt(pos int, key []string, encoded string) error {
+	line := formatKey(key) + " = " + encoded + "\n"
+	if pos > 0 && d.text[pos-1] != '\n' {
+		line = "\n" + line
+	}
+	return d.replace(pos, pos, line)
+}
+
+func (d *Document) appendTable(path []string, key, encoded string) error {
+	return d.insertBlock(d.tableEnd(path), tableHeader(path, false)+formatKey([]string{key})+" = "+encoded+"\n")
+}
+
+// setArray replaces every element of the array of tables at path with items
+func (d *Document) setArray(path []string, items []map[string]interface{}) error {
+	var block strings.Builder
+	for _, item := range items {
+		block.WriteString(tableHeader(path, true))
+		keys := make([]string, 0, len(item))
+		for k := range item {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			encoded, err := encodeTOMLValue(item[k])
+			if err != nil {
+				return fmt.Errorf("cannot encode %s.%s: %w", strings.Join(path, "."), k, err)
+			}
+			block.WriteString(formatKey([]string{k}) + " = " + encoded + "\n")
+		}
+		block.WriteString("\n")
+	}
+
+	elements := d.elements(path)
+	if len(elements) == 0 {
+		if d.isTable(path) {
+			return fmt.Errorf("cannot set %s: it is a table", strings.Join(path, "."))
+		}
+		return d.insertBlock(d.tableEnd(path), strings.TrimSuffix(block.String(), "\n"))
+	}
+
+	// Cut elements back to front so offsets stay valid, then put the new
+	// ones where the first element was
+	text := d.text
+	for i := len(elements) - 1; i >= 0; i-- {
+		t := elements[i]
+		text = text[:t.start] + text[d.regionEnd(t):]
+	}
+	first := elements[0].start
+	return d.setText(text[:first] + block.String() + text[first:])
+}
+
+// ensureElements creates missing array elements on path. Only the element
+// right after the last existing one can be created.
+func (d *Document) ensureElements(path []string) error {
+	for i := range path {
+		m := indexRe.FindStringSubmatch(path[i])
+		if m == nil || d.table(path[:i+1]) != nil {
+			continue
+		}
+		idx, _ := strconv.Atoi(m[2])
+		array := append(append([]string{}, path[:i]...), m[1])
+		elements := d.elements(array)
+		if idx != len(elements) {
+			return fmt.Errorf("cannot set %s: index %d out of range, %s has %d elements",
+				strings.Join(path, "."), idx, strings.Join(array, "."), len(elements))
+		}
+		// Keep the elements together: the new one goes after the last
+		pos := d.tableEnd(path[:i+1])
+		if len(elements) > 0 {
+			pos = d.regionEnd(elements[len(elements)-1])
+		}
+		if err := d.insertBlock(pos, tableHeader(path[:i+1], true)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// elements returns the [[...]] headers of the array of tables at path
+func (d *Document) elements(path []string) []*docTable {
+	var result []*docTable
+	for _, t := range d.tables {
+		if !t.isArray || len(t.path) != len(path) || !hasPrefix(t.path, path[:len(path)-1]) {
+			continue
+		}
+		if m := indexRe.FindStringSubmatch(t.path[len(t.path)-1]); m != nil && m[1] == path[len(path)-1] {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// tableEnd is where a new table at path goes: at the end of the array
+// element it belongs to, or at the end of the document
+func (d *Document) tableEnd(path []string) int {
+	for i := len(path) - 2; i >= 0; i-- {
+		if indexRe.MatchString(path[i]) {
+			if t := d.table(path[:i+1]); t != nil {
+				return d.regionEnd(t)
+			}
+		}
+	}
+	return len(d.text)
+}
+
+// regionEnd returns the offset of the first header after t that is not one
+// of its sub-tables
+func (d *Document) regionEnd(t *docTable) int {
+	for _, other := range d.tables {
+		if other.start > t.start && !(len(other.path) > len(t.path) && hasPrefix(other.path, t.path)) {
+			return other.start
+		}
+	}
+	return len(d.text)
+}
+
+// insertBlock inserts table text at pos, separated by blank lines
+func (d *Document) insertBlock(pos int, block string) error {
+	before, after := d.text[:pos], d.text[pos:]
+	if before != "" && !strings.HasSuffix(before, "\n") {
+		before += "\n"
+	}
+	if before != "" && !strings.HasSuffix(before, "\n\n") {
+		before += "\n"
+	}
+	if after != "" {
+		block += "\n"
+	}
+	return d.setText(before + block + after)
+}
+
+// tableArray returns v as a list of tables if it is a non-empty array whose
+// items are all tables
+func tableArray(v interface{}) ([]map[string]interface{}, bool) {
+	switch x := v.(type) {
+	case []map[string]interface{}:
+		return x, len(x) > 0
+	case []interface{}:
+		items := make([]map[string]interface{}, len(x))
+		for i, item := range x {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			items[i] = m
+		}
+		return items, len(items) > 0
+	}
+	return nil, false
+}
+
+// tableHeader formats the header line for path, dropping element indexes
+func tableHeader(path []string, isArray bool) string {
+	names := make([]string, len(path))
+	for i, p := range path {
+		if m := indexRe.FindStringSubmatch(p); m != nil {
+			p = m[1]
+		}
+		names[i] = p
+	}
+	if isArray {
+		return "[[" + formatKey(names) + "]]\n"
+	}
+	return "[" + formatKey(names) + "]\n"
+}
+
+func (d *Document) replace(start, end int, s string) error {
+	return d.setText(d.text[:start] + s + d.text[end:])
+}
+
+// setText re-parses after every edit; the result must still be valid TOML
+func (d *Document) setText(text string) error {
+	if _, err := toml.Decode(text, new(map[string]interface{})); err != nil {
+		return fmt.Errorf("edit produced invalid TOML: %w", err)
+	}
+	d.text = text
+	return d.parse()
+}
+
+// parse indexes tables and key/value entries of d.text
+func (d *Document) parse() error {
+	root := &docTable{}
+	d.tables = []*docTable{root}
+	d.entries = nil
+
+	current := root
+	arrayCounts := make(map[string]int)
+	text := d.text
+	pos := 0
+	for pos < len(text) {
+		lineStart := pos
+		pos = skipSpace(text, pos)
+		if pos >= len(text) {
+			break
+		}
+
+		switch c := text[pos]; {
+		case c == '\n' || c == '\r':
+			pos = lineEnd(text, pos)
+			continue
+		case c == '#':
+			pos = lineEnd(text, pos)
+			continue
+		case c == '[':
+			isArray := strings.HasPrefix(text[pos:], "[[")
+			if isArray {
+				pos += 2
+			} else {
+				pos++
+			}
+			path, next, err := parseKey(text, pos)
+			if err != nil {
+				return err
+			}
+			// Sub-tables of an array element belong to its latest entry
+			for i := 1; i < len(path); i++ {
+				if n := arrayCounts[strings.Join(path[:i], ".")]; n > 0 {
+					path[i-1] = fmt.Sprintf("%s[%d]", path[i-1], n-1)
+				}
+			}
+			if isArray {
+				name := strings.Join(path, ".")
+				path[len(path)-1] = fmt.Sprintf("%s[%d]", path[len(path)-1], arrayCounts[name])
+				arrayCounts[name]++
+			}
+			current = &docTable{path: path, start: lineStart, isArray: isArray}
+			d.tables = append(d.tables, current)
+			pos = lineEnd(text, next)
+		default:
+			key, next, err := parseKey(text, pos)
+			if err != nil {
+				return err
+			}
+			next = skipSpace(text, next)
+			if next >= len(text) || text[next] != '=' {
+				return fmt.Errorf("expected '=' after key at offset %d", next)
+			}
+			valueStart := skipSpace(text, next+1)
+			valueEnd := scanValue(text, valueStart)
+			pos = lineEnd(text, valueEnd)
+
+			path := append(append([]string{}, current.path...), key...)
+			d.entries = append(d.entries, &docEntry{
+				path:       path,
+				table:      current,
+				keyStart:   lineStart,
+				valueStart: valueStart,
+				valueEnd:   valueEnd,
+				lineEnd:    pos,
+			})
+		}
+	}
+	return nil
+}
+
+// parseKey parses a dotted key up to '=' or ']' and returns its segments
+func parseKey(text string, pos int) ([]string, int, error) {
+	var parts []string
+	for {
+		pos = skipSpace(text, pos)
+		if pos >= len(text) {
+			return nil, pos, fmt.Errorf("unexpected end of input in key")
+		}
+
+		switch text[pos] {
+		case '"':
+			end := pos + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			s, err := strconv.Unquote(text[pos : end+1])
+			if err != nil {
+				return nil, pos, fmt.Errorf("invalid quoted key at offset %d", pos)
+			}
+			parts = append(parts, s)
+			pos = end + 1
+		case '\'':
+			end := strings.IndexByte(text[pos+1:], '\'')
+			if end == -1 {
+				return nil, pos, fmt.Errorf("unterminated key at offset %d", pos)
+			}
+			parts = append(parts, text[pos+1:pos+1+end])
+			pos = pos + end + 2
+		default:
+			end := pos
+			for end < len(text) && bareKeyRe.MatchString(text[end:end+1]) {
+				end++
+			}
+			if end == pos {
+				return nil, pos, fmt.Errorf("invalid key at offset %d", pos)
+			}
+			parts = append(parts, text[pos:end])
+			pos = end
+		}
+
+		pos = skipSpace(text, pos)
+		if pos < len(text) && text[pos] == '.' {
+			pos++
+			continue
+		}
+		// Step over the closing bracket(s) of a table header
+		for pos < len(text) && text[pos] == ']' {
+			pos++
+		}
+		return parts, pos, nil
+	}
+}
+
+// scanValue returns the offset just past the value starting at pos,
+// following strings, arrays and inline tables across lines
+func scanValue(text string, pos int) int {
+	depth := 0
+	end := pos
+	for pos < len(text) {
+		switch {
+		case strings.HasPrefix(text[pos:], `"""`):
+			pos = skipMultiline(text, pos+3, `"""`, true)
+		case strings.HasPrefix(text[pos:], `'''`):
+			pos = skipMultiline(text, pos+3, `'''`, false)
+		case text[pos] == '"':
+			pos++
+			for pos < len(text) && text[pos] != '"' && text[pos] != '\n' {
+				if text[pos] == '\\' {
+					pos++
+				}
+				pos++
+			}
+			pos++
+		case text[pos] == '\'':
+			pos++
+			for pos < len(text) && text[pos] != '\'' && text[pos] != '\n' {
+				pos++
+			}
+			pos++
+		case text[pos] == '[' || text[pos] == '{':
+			depth++
+			pos++
+		case text[pos] == ']' || text[pos] == '}':
+			depth--
+			pos++
+		case text[pos] == '#':
+			if depth == 0 {
+				return end
+			}
+			for pos < len(text) && text[pos] != '\n' {
+				pos++
+			}
+			continue
+		case text[pos] == '\n' || text[pos] == '\r':
+			if depth == 0 {
+				return end
+			}
+			pos++
+			continue
+		case text[pos] == ' ' || text[pos] == '\t':
+			pos++
+			continue
+		default:
+			pos++
+		}
+		if pos > len(text) {
+			pos = len(text)
+		}
+		end = pos
+	}
+	return end
+}
+
+func skipMultiline(text string, pos int, delim string, escapes bool) int {
+	for pos < len(text) {
+		if escapes && text[pos] == '\\' {
+			pos += 2
+			continue
+		}
+		if strings.HasPrefix(text[pos:], delim) {
+			pos += len(delim)
+			// Up to two quotes may directly precede the closing delimiter
+			for i := 0; i < 2 && pos < len(text) && text[pos] == delim[0]; i++ {
+				pos++
+			}
+			return pos
+		}
+		pos++
+	}
+	return pos
+}
+
+func skipSpace(text string, pos int) int {
+	for pos < len(text) && (text[pos] == ' ' || text[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+// lineEnd returns the offset just past the newline ending the line at pos
+func lineEnd(text string, pos int) int {
+	if i := strings.IndexByte(text[pos:], '\n'); i != -1 {
+		return pos + i + 1
+	}
+	return len(text)
+}
+
+func formatKey(path []string) string {
+	parts := make([]string, len(path))
+	for i, p := range path {
+		if bareKeyRe.MatchString(p) {
+			parts[i] = p
+		} else {
+			parts[i] = quoteTOMLString(p)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// encodeTOMLValue formats v as an inline TOML value
+func encodeTOMLValue(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", fmt.Errorf("nil value")
+	case string:
+		return quoteTOMLString(x), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case time.Time:
+		return x.Format(time.RFC3339Nano), nil
+	case float32:
+		return formatTOMLFloat(float64(x)), nil
+	case float64:
+		return formatTOMLFloat(x), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Slice, reflect.Array:
+		items := make([]string, rv.Len())
+		for i := range items {
+			s, err := encodeTOMLValue(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case reflect.Map:
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, fmt.Sprint(k.Interface()))
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			s, err := encodeTOMLValue(rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key())).Interface())
+			if err != nil {
+				return "", err
+			}
+			items[i] = formatKey([]string{k}) + " = " + s
+		}
+		if len(items) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("unsupported type %T", v)
+}
+
+func formatTOMLFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eEn") {
+		s += ".0"
+	}
+	return s
+}
+
+func quoteTOMLString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func equalPath(a, b []string) bool {
+	return len(a) == len(b) && hasPrefix(a, b)
+}
+
+func hasPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
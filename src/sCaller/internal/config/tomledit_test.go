@@ -0,0 +1,187 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files of the tomledit tests")
+
+// tomlEdit is one Set (or Unset if value is nil) applied to a document
+type tomlEdit struct {
+	path  string
+	value interface{}
+}
+
+// splitPath splits a dotted test path; segments in double quotes keep
+// their dots
+func splitPath(path string) []string {
+	var parts []string
+	for path != "" {
+		if strings.HasPrefix(path, `"`) {
+			end := strings.Index(path[1:], `"`) + 1
+			parts = append(parts, path[1:end])
+			path = strings.TrimPrefix(path[end+1:], ".")
+			continue
+		}
+		part, rest, _ := strings.Cut(path, ".")
+		parts = append(parts, part)
+		path = rest
+	}
+	return parts
+}
+
+// Each case edits testdata/tomledit/<name>.in.toml and compares the result
+// with <name>.golden.toml; go test -run TestDocumentGolden -update rewrites
+// the golden files
+func TestDocumentGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits []tomlEdit
+	}{
+		{"comments", []tomlEdit{
+			{"moniker", "validator-1"},
+			{"fast_sync", false},
+			{"p2p.seeds", "id@1.2.3.4:26656"},
+			{"p2p.max_num_inbound_peers", 100},
+			{"p2p.pex", true},
+			{"mempool.size", 10000},
+			{"log_level", "info"},
+		}},
+		{"newtable", []tomlEdit{
+			{"api.address", "tcp://0.0.0.0:1317"},
+			{"grpc.enable", true},
+			{"state-sync.snapshot-interval", 1000},
+			{"telemetry.global-labels", []interface{}{[]string{"chain_id", "localnet-1"}}},
+			{"a.b.c", "nested"},
+		}},
+		{"dotted", []tomlEdit{
+			{"name.last", "Werner"},
+			{"name.middle", "P"},
+			{"app.telemetry.enabled", true},
+			{"app.telemetry.prometheus-retention-time", 60},
+			{`app.site."example.com"`, false},
+			{"other.x", 2},
+		}},
+		{"arrays", []tomlEdit{
+			{"validator[1].addr", "tcp://10.0.0.3:26659"},
+			{"validator[2].chain_id", "localnet-1"},
+			{"validator[2].addr", "unix:///run/sekaid.sock"},
+			{"providers.softsign[0].path", "/tmkms/secrets/consensus.json"},
+			{"chain", []map[string]interface{}{
+				{"id": "localnet-1", "key_format": map[string]string{"type": "bech32"}},
+				{"id": "localnet-2", "key_format": map[string]string{"type": "bech32"}},
+			}},
+		}},
+		{"strings", []tomlEdit{
+			{"description", "one line now"},
+			{"regex", `back\slash`},
+			{"path", "/home/node"},
+			{"lines", "a\nb"},
+			{"new", `say "hi"`},
+		}},
+		{"unset", []tomlEdit{
+			{"fast_sync", nil},
+			{"p2p.seeds", nil},
+			{"mempool.recheck", nil},
+			{"extra.note", nil},
+			{"extra.dotted.key", nil},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := os.ReadFile(filepath.Join("testdata", "tomledit", tt.name+".in.toml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := ParseDocument(string(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range tt.edits {
+				if e.value == nil {
+					err = d.Unset(splitPath(e.path))
+				} else {
+					err = d.Set(splitPath(e.path), e.value)
+				}
+				if err != nil {
+					t.Fatalf("%s: %v", e.path, err)
+				}
+			}
+
+			golden := filepath.Join("testdata", "tomledit", tt.name+".golden.toml")
+			if *update {
+				if err := os.WriteFile(golden, []byte(d.String()), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := d.String(); got != string(want) {
+				t.Errorf("edited document differs from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestDocumentGetStrings(t *testing.T) {
+	in, err := os.ReadFile(filepath.Join("testdata", "tomledit", "strings.in.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := ParseDocument(string(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"description": "Multi-line \"basic\" string\nwith an embedded = sign and a # hash\n",
+		"regex":       `I [dw]on't need \d{2} apples`,
+		"path":        `C:\Users\nodejs\templates`,
+		"quoted":      "tab\there \"quoted\"",
+		"lines":       "first line\n[not.a.table]\nkey = \"not a key\"\n",
+		"last":        "end",
+	} {
+		if got, ok := d.Get([]string{path}); !ok || got != want {
+			t.Errorf("Get(%s) = %q, %v, want %q", path, got, ok, want)
+		}
+	}
+	// Lines inside multi-line strings are not tables or keys
+	if d.isTable([]string{"not", "a", "table"}) {
+		t.Error("header inside a multi-line string parsed as a table")
+	}
+}
+
+func TestDocumentRejectsInvalidEdits(t *testing.T) {
+	in, err := os.ReadFile(filepath.Join("testdata", "tomledit", "arrays.in.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := ParseDocument(string(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []tomlEdit{
+		{"validator[5].addr", "tcp://10.0.0.9:26659"},
+		{"validator[0]", "x"},
+		{"chain[0].id.sub", "x"},
+		{"missing", nil},
+		{"validator[0]", nil},
+	} {
+		if e.value == nil {
+			err = d.Unset(splitPath(e.path))
+		} else {
+			err = d.Set(splitPath(e.path), e.value)
+		}
+		if err == nil {
+			t.Errorf("edit of %s succeeded", e.path)
+		}
+	}
+	if got := d.String(); got != string(in) {
+		t.Errorf("refused edits changed the document:\n%s", got)
+	}
+}
@@ -1,6 +1,8 @@
 package genesis
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -70,3 +72,31 @@ func normalizeGenesis(raw json.RawMessage) ([]byte, error) {
 	// Re-marshal with indentation for readability
 	return json.MarshalIndent(genesis, "", "  ")
 }
+
+// Checksum returns the sha256 hex digest of the genesis file at path
+func Checksum(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ChainID returns the chain_id of the genesis file at path
+func ChainID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var g struct {
+		ChainID string `json:"chain_id"`
+	}
+	if err := json.Unmarshal(data, &g); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if g.ChainID == "" {
+		return "", fmt.Errorf("%s has no chain_id", path)
+	}
+	return g.ChainID, nil
+}
@@ -0,0 +1,189 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cosmos/go-bip39"
+)
+
+// Amino type names used in priv_validator_key.json and node_key.json
+const (
+	PubKeyEd25519Type  = "tendermint/PubKeyEd25519"
+	PrivKeyEd25519Type = "tendermint/PrivKeyEd25519"
+)
+
+// ConsensusKey is an ed25519 validator consensus key
+type ConsensusKey struct {
+	priv ed25519.PrivateKey
+}
+
+// TypedKey is a key in amino JSON form
+type TypedKey struct {
+	Type  string `json:"type"`
+	Value []byte `json:"value"`
+}
+
+// PrivValidatorKey is the content of priv_validator_key.json
+type PrivValidatorKey struct {
+	Address string   `json:"address"`
+	PubKey  TypedKey `json:"pub_key"`
+	PrivKey TypedKey `json:"priv_key"`
+}
+
+// NodeKey is the content of node_key.json
+type NodeKey struct {
+	PrivKey TypedKey `json:"priv_key"`
+}
+
+// GenerateConsensusKey creates a random consensus key
+func GenerateConsensusKey() (*ConsensusKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsensusKey{priv: priv}, nil
+}
+
+// ConsensusKeyFromMnemonic derives a consensus key from a mnemonic and BIP39
+// passphrase: the ed25519 seed is SHA256 of the BIP39 seed. This derivation
+// is specific to scaller; sekaid init --recover and other wallets derive a
+// different key from the same mnemonic, so the key can only be recovered
+// with scaller.
+func ConsensusKeyFromMnemonic(mnemonic, passphrase string) (*ConsensusKey, error) {
+	mnemonic = NormalizeMnemonic(mnemonic)
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	seed := bip39.NewSeed(mnemonic, passphrase)
+	defer zero(seed)
+	secret := sha256.Sum256(seed)
+	defer zero(secret[:])
+	return &ConsensusKey{priv: ed25519.NewKeyFromSeed(secret[:])}, nil
+}
+
+// LoadConsensusKey reads priv_validator_key.json or node_key.json
+func LoadConsensusKey(path string) (*ConsensusKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(data)
+	var file NodeKey
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	defer zero(file.PrivKey.Value)
+	if file.PrivKey.Type != PrivKeyEd25519Type || len(file.PrivKey.Value) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%s does not hold an ed25519 private key", path)
+	}
+	return ConsensusKeyFromBytes(file.PrivKey.Value)
+}
+
+// ConsensusKeyFromBytes wraps a 32 byte ed25519 seed or a 64 byte private
+// key, checking that the public half of the latter matches its seed
+func ConsensusKeyFromBytes(b []byte) (*ConsensusKey, error) {
+	switch len(b) {
+	case ed25519.SeedSize:
+		return &ConsensusKey{priv: ed25519.NewKeyFromSeed(b)}, nil
+	case ed25519.PrivateKeySize:
+		priv := ed25519.NewKeyFromSeed(b[:ed25519.SeedSize])
+		if !bytes.Equal(priv[ed25519.SeedSize:], b[ed25519.SeedSize:]) {
+			zero(priv)
+			return nil, errors.New("ed25519 public key does not match the private key")
+		}
+		return &ConsensusKey{priv: priv}, nil
+	}
+	return nil, fmt.Errorf("ed25519 key must be 32 or 64 bytes, got %d", len(b))
+}
+
+// ParseConsensusKey reads an ed25519 key in any of the forms validators keep
+// them: priv_validator_key.json or node_key.json, a TMKMS softsign key
+// (base64 of the seed or full key) or the raw 32 or 64 bytes
+func ParseConsensusKey(data []byte) (*ConsensusKey, error) {
+	trimmed := bytes.TrimSpace(data)
+	// Raw keys may start with '{' as well, so fall through on a parse error
+	var file NodeKey
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		err := json.Unmarshal(trimmed, &file)
+		defer zero(file.PrivKey.Value)
+		if err != nil && len(data) != ed25519.SeedSize && len(data) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("failed to parse key JSON: %w", err)
+		}
+	}
+	if file.PrivKey.Type != "" {
+		if file.PrivKey.Type != PrivKeyEd25519Type {
+			return nil, fmt.Errorf("unsupported key type %q", file.PrivKey.Type)
+		}
+		return ConsensusKeyFromBytes(file.PrivKey.Value)
+	}
+
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(trimmed)))
+	defer zero(decoded)
+	if n, err := base64.StdEncoding.Decode(decoded, trimmed); err == nil {
+		if k, err := ConsensusKeyFromBytes(decoded[:n]); err == nil {
+			return k, nil
+		}
+	}
+	return ConsensusKeyFromBytes(data)
+}
+
+// PubKey returns the ed25519 public key
+func (c *ConsensusKey) PubKey() ed25519.PublicKey {
+	return c.priv.Public().(ed25519.PublicKey)
+}
+
+// PrivKey returns the 64 byte ed25519 private key
+func (c *ConsensusKey) PrivKey() ed25519.PrivateKey {
+	return c.priv
+}
+
+// Address returns the 20 byte consensus address, SHA256(pubkey)[:20]
+func (c *ConsensusKey) Address() []byte {
+	sum := sha256.Sum256(c.PubKey())
+	return sum[:20]
+}
+
+// HexAddress returns the address as written in priv_validator_key.json and
+// commit signatures
+func (c *ConsensusKey) HexAddress() string {
+	return strings.ToUpper(hex.EncodeToString(c.Address()))
+}
+
+// ValconsAddress returns the kiravalcons1… address
+func (c *ConsensusKey) ValconsAddress() string {
+	return mustBech32(ValconsPrefix, c.Address())
+}
+
+// File returns the priv_validator_key.json content of c
+func (c *ConsensusKey) File() PrivValidatorKey {
+	return PrivValidatorKey{
+		Address: c.HexAddress(),
+		PubKey:  TypedKey{Type: PubKeyEd25519Type, Value: c.PubKey()},
+		PrivKey: TypedKey{Type: PrivKeyEd25519Type, Value: c.priv},
+	}
+}
+
+// NodeKeyFile returns the node_key.json content of c
+func (c *ConsensusKey) NodeKeyFile() NodeKey {
+	return NodeKey{PrivKey: TypedKey{Type: PrivKeyEd25519Type, Value: c.priv}}
+}
+
+// NodeID returns the P2P node ID of c used as a node key, the lowercase hex address
+func (c *ConsensusKey) NodeID() string {
+	return hex.EncodeToString(c.Address())
+}
+
+// Zero overwrites the private key in memory
+func (c *ConsensusKey) Zero() {
+	zero(c.priv)
+}
@@ -0,0 +1,107 @@
+package keys
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// DefaultPath is the Cosmos HD path of the first account key (coin type 118)
+const DefaultPath = "m/44'/118'/0'/0/0"
+
+// hardened is added to path indices marked with '
+const hardened = 0x80000000
+
+// ParsePath parses a BIP32 path such as m/44'/118'/0'/0/0
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("invalid HD path %q: must start with m/", path)
+	}
+	indices := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			offset = hardened
+			part = part[:len(part)-1]
+		}
+		n, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HD path %q: bad index %q", path, part)
+		}
+		indices = append(indices, uint32(n)+offset)
+	}
+	return indices, nil
+}
+
+// DerivePrivKey derives the secp256k1 private key at path from a BIP39 seed
+// following BIP32
+func DerivePrivKey(seed []byte, path string) ([]byte, error) {
+	indices, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	defer zero(sum)
+	var key secp256k1.ModNScalar
+	if overflow := key.SetByteSlice(sum[:32]); overflow || key.IsZero() {
+		return nil, fmt.Errorf("seed yields an invalid master key")
+	}
+	defer key.Zero()
+	chain := append([]byte(nil), sum[32:]...)
+	defer zero(chain)
+
+	for _, index := range indices {
+		data := make([]byte, 0, 37)
+		if index >= hardened {
+			data = append(data, 0)
+			b := key.Bytes()
+			data = append(data, b[:]...)
+			zero(b[:])
+		} else {
+			data = append(data, secp256k1.NewPrivateKey(&key).PubKey().SerializeCompressed()...)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		mac := hmac.New(sha512.New, chain)
+		mac.Write(data)
+		zero(data)
+		sum := mac.Sum(nil)
+
+		var il secp256k1.ModNScalar
+		overflow := il.SetByteSlice(sum[:32])
+		key.Add(&il)
+		il.Zero()
+		if overflow || key.IsZero() {
+			zero(sum)
+			return nil, fmt.Errorf("index %d of %s yields an invalid key", index, path)
+		}
+		copy(chain, sum[32:])
+		zero(sum)
+	}
+	b := key.Bytes()
+	return b[:], nil
+}
+
+// pubKey returns the compressed secp256k1 public key of priv
+func pubKey(priv []byte) []byte {
+	k := secp256k1.PrivKeyFromBytes(priv)
+	defer k.Zero()
+	return k.PubKey().SerializeCompressed()
+}
+
+// validPrivKey reports whether priv is a secp256k1 scalar in [1, n)
+func validPrivKey(priv []byte) bool {
+	var k secp256k1.ModNScalar
+	defer k.Zero()
+	overflow := k.SetByteSlice(priv)
+	return len(priv) == 32 && !overflow && !k.IsZero()
+}
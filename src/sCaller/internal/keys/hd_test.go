@@ -0,0 +1,55 @@
+package keys
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// BIP32 test vector 1
+func TestDerivePrivKeyBIP32Vector1(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		path string
+		priv string
+		pub  string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35", "0339a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c2"},
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea", "035a784662a4a20a65bf6aab9ae98a6c068a81c52e4b032c0fb5400c706cfccc56"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368", "03501e454bf00751f24b1b489aa925215d66af2234e3891c3b21a52bedb3cd711c"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca", "0357bfe1e341d01c69fe5654309956cbea516822fba8a601743a012a7896ee8dc2"},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4", "02e8445082a72f29b75ca48748a914df60622a609cacfce8ed0e35804560741d29"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8", "022a471424da5e657499d1ff51cb43c47481a03b1e77f951fe64cec9f5a48f7011"},
+	}
+	for _, tt := range tests {
+		priv, err := DerivePrivKey(seed, tt.path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if got := hex.EncodeToString(priv); got != tt.priv {
+			t.Errorf("%s: private key %s, want %s", tt.path, got, tt.priv)
+		}
+		if got := hex.EncodeToString(pubKey(priv)); got != tt.pub {
+			t.Errorf("%s: public key %s, want %s", tt.path, got, tt.pub)
+		}
+	}
+}
+
+func TestFromMnemonicCosmosAddress(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	k, err := FromMnemonic(mnemonic, "", DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Zero()
+	if got, want := mustBech32("cosmos", k.Address()), "cosmos19rl4cm2hmr8afy4kldpxz3fka4jguq0auqdal4"; got != want {
+		t.Errorf("address %s, want %s", got, want)
+	}
+}
+
+func TestParsePathRejectsInvalid(t *testing.T) {
+	for _, path := range []string{"", "44'/118'", "m/x", "m/2147483648", "m//0"} {
+		if _, err := ParsePath(path); err == nil {
+			t.Errorf("ParsePath(%q) succeeded", path)
+		}
+	}
+}
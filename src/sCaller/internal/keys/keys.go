@@ -0,0 +1,124 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/cosmos/go-bip39"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Bech32 prefixes of KIRA addresses
+const (
+	AccountPrefix = "kira"
+	ValoperPrefix = "kiravaloper"
+	ValconsPrefix = "kiravalcons"
+)
+
+// Key is a secp256k1 account key
+type Key struct {
+	priv []byte
+	pub  []byte
+}
+
+// FromMnemonic derives the account key at path (DefaultPath if empty) from a
+// BIP39 mnemonic and optional BIP39 passphrase
+func FromMnemonic(mnemonic, passphrase, path string) (*Key, error) {
+	mnemonic = NormalizeMnemonic(mnemonic)
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+	seed := bip39.NewSeed(mnemonic, passphrase)
+	defer zero(seed)
+
+	priv, err := DerivePrivKey(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	return FromPrivKey(priv)
+}
+
+// FromPrivKey wraps a raw 32 byte secp256k1 private key
+func FromPrivKey(priv []byte) (*Key, error) {
+	if len(priv) != 32 {
+		return nil, fmt.Errorf("private key must be 32 bytes, got %d", len(priv))
+	}
+	if !validPrivKey(priv) {
+		return nil, fmt.Errorf("private key out of range")
+	}
+	priv = append([]byte(nil), priv...)
+	return &Key{priv: priv, pub: pubKey(priv)}, nil
+}
+
+// PrivKey returns the raw private key
+func (k *Key) PrivKey() []byte {
+	return k.priv
+}
+
+// PubKey returns the 33 byte compressed public key
+func (k *Key) PubKey() []byte {
+	return k.pub
+}
+
+// Address returns the 20 byte account address, RIPEMD160(SHA256(pubkey))
+func (k *Key) Address() []byte {
+	sum := sha256.Sum256(k.pub)
+	h := ripemd160.New()
+	h.Write(sum[:])
+	return h.Sum(nil)
+}
+
+// AccAddress returns the kira1… account address
+func (k *Key) AccAddress() string {
+	return mustBech32(AccountPrefix, k.Address())
+}
+
+// ValoperAddress returns the kiravaloper1… validator operator address
+func (k *Key) ValoperAddress() string {
+	return mustBech32(ValoperPrefix, k.Address())
+}
+
+// Zero overwrites the private key in memory
+func (k *Key) Zero() {
+	zero(k.priv)
+}
+
+// Matches reports whether address is the account or operator address of k
+func (k *Key) Matches(address string) (bool, error) {
+	hrp, data, err := Bech32Decode(address)
+	if err != nil {
+		return false, err
+	}
+	if hrp != AccountPrefix && hrp != ValoperPrefix {
+		return false, fmt.Errorf("%s is not a %s or %s address", address, AccountPrefix, ValoperPrefix)
+	}
+	return bytes.Equal(data, k.Address()), nil
+}
+
+// ConvertAddress re-encodes a bech32 address under another prefix, e.g. a
+// kira1… account address as its kiravaloper1… form
+func ConvertAddress(address, prefix string) (string, error) {
+	_, data, err := Bech32Decode(address)
+	if err != nil {
+		return "", err
+	}
+	return Bech32Encode(prefix, data)
+}
+
+func mustBech32(hrp string, data []byte) string {
+	s, err := Bech32Encode(hrp, data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,108 @@
+package keys
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/go-bip39"
+)
+
+// DefaultWords is the mnemonic length sekaid generates
+const DefaultWords = 24
+
+// MnemonicWords lists the supported mnemonic lengths
+var MnemonicWords = []int{12, 15, 18, 21, 24}
+
+// ErrInvalidMnemonic is returned for mnemonics with unknown words or a bad checksum
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// NewMnemonic generates a mnemonic of words words from the system random
+// source. Non-empty extra entropy (e.g. dice rolls) is hashed together with
+// it, so the result is no weaker than either source alone.
+func NewMnemonic(words int, extra []byte) (string, error) {
+	bits, err := entropyBits(words)
+	if err != nil {
+		return "", err
+	}
+	entropy, err := bip39.NewEntropy(bits)
+	if err != nil {
+		return "", err
+	}
+	defer zero(entropy)
+
+	if len(extra) > 0 {
+		h := sha256.New()
+		h.Write(entropy)
+		h.Write(extra)
+		sum := h.Sum(nil)
+		defer zero(sum)
+		copy(entropy, sum)
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// MnemonicFromEntropy encodes exactly the given entropy (16 to 32 bytes in
+// steps of 4) as a mnemonic, for entropy produced offline
+func MnemonicFromEntropy(entropy []byte) (string, error) {
+	if _, err := entropyBits(len(entropy) * 3 / 4); err != nil || len(entropy)%4 != 0 {
+		return "", fmt.Errorf("entropy must be 16, 20, 24, 28 or 32 bytes, got %d", len(entropy))
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// ValidateMnemonic checks the words and checksum of a mnemonic
+func ValidateMnemonic(mnemonic string) error {
+	words := strings.Fields(mnemonic)
+	if _, err := entropyBits(len(words)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMnemonic, err)
+	}
+	for i, w := range words {
+		// Only the position is reported; a mistyped word is still secret
+		if _, ok := bip39.ReverseWordMap[strings.ToLower(w)]; !ok {
+			return fmt.Errorf("%w: word %d is not in the BIP39 word list", ErrInvalidMnemonic, i+1)
+		}
+	}
+	// go-bip39's IsMnemonicValid only checks the words, not the checksum
+	entropy, err := bip39.MnemonicToByteArray(NormalizeMnemonic(mnemonic))
+	if err != nil {
+		return fmt.Errorf("%w: bad checksum", ErrInvalidMnemonic)
+	}
+	zero(entropy)
+	return nil
+}
+
+// NormalizeMnemonic lowercases a mnemonic and separates its words by single spaces
+func NormalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+}
+
+// CheckMnemonic derives the key of mnemonic at path and fails unless its
+// account or operator address equals address
+func CheckMnemonic(mnemonic, passphrase, path, address string) (*Key, error) {
+	k, err := FromMnemonic(mnemonic, passphrase, path)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := k.Matches(address)
+	if err != nil {
+		k.Zero()
+		return nil, err
+	}
+	if !ok {
+		derived := k.AccAddress()
+		k.Zero()
+		return nil, fmt.Errorf("mnemonic derives %s, expected %s", derived, address)
+	}
+	return k, nil
+}
+
+func entropyBits(words int) (int, error) {
+	for _, n := range MnemonicWords {
+		if n == words {
+			return words * 32 / 3, nil
+		}
+	}
+	return 0, fmt.Errorf("mnemonic must have 12, 15, 18, 21 or 24 words, got %d", words)
+}
@@ -0,0 +1,497 @@
+package keys
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/cosmos/go-bip39"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrInvalidShare is returned for SLIP-39 shares with unknown words, a bad
+// checksum or an invalid header
+var ErrInvalidShare = errors.New("invalid SLIP-39 share")
+
+// SLIP-39 constants
+const (
+	slip39MinWords       = 20
+	slip39BaseIterations = 10000
+	slip39Rounds         = 4
+	slip39SecretIndex    = 255
+	slip39DigestIndex    = 254
+	slip39DigestLen      = 4
+	slip39MaxShares      = 16
+	// slip39Exponent is the iteration exponent of new shares, as in the
+	// reference implementation
+	slip39Exponent = 1
+)
+
+// ShamirSecretPrefix starts the master secrets scaller recovers a mnemonic
+// from: the prefix followed by the mnemonic's BIP39 entropy. SLIP-39 wallets
+// use the master secret as the BIP32 seed instead, which cannot be turned
+// back into a mnemonic, so their shares are refused rather than recovered
+// into an unrelated key.
+const ShamirSecretPrefix = "scaller:"
+
+// Share is one decoded SLIP-39 share
+type Share struct {
+	ID              int
+	Extendable      bool
+	Exponent        int
+	GroupIndex      int
+	GroupThreshold  int
+	GroupCount      int
+	MemberIndex     int
+	MemberThreshold int
+	Value           []byte
+}
+
+// ParseShare decodes a SLIP-39 share and verifies its checksum
+func ParseShare(mnemonic string) (*Share, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) < slip39MinWords {
+		return nil, fmt.Errorf("%w: %d words, at least %d expected", ErrInvalidShare, len(words), slip39MinWords)
+	}
+	values := make([]int, len(words))
+	for i, w := range words {
+		v, ok := slip39Index[w]
+		if !ok {
+			return nil, fmt.Errorf("%w: word %d is not in the SLIP-39 word list", ErrInvalidShare, i+1)
+		}
+		values[i] = v
+	}
+
+	extendable := values[1]>>4&1 == 1
+	if rs1024Polymod(customization(extendable), values) != 1 {
+		return nil, fmt.Errorf("%w: bad checksum", ErrInvalidShare)
+	}
+
+	idExp := values[0]<<10 | values[1]
+	params := values[2]<<10 | values[3]
+	s := &Share{
+		ID:              idExp >> 5,
+		Extendable:      extendable,
+		Exponent:        idExp & 0xf,
+		GroupIndex:      params >> 16,
+		GroupThreshold:  params>>12&0xf + 1,
+		GroupCount:      params>>8&0xf + 1,
+		MemberIndex:     params >> 4 & 0xf,
+		MemberThreshold: params&0xf + 1,
+	}
+	if s.GroupThreshold > s.GroupCount {
+		return nil, fmt.Errorf("%w: group threshold %d exceeds group count %d", ErrInvalidShare, s.GroupThreshold, s.GroupCount)
+	}
+
+	// The share value is left-padded with at most 8 zero bits to whole words
+	valueWords := values[4 : len(values)-3]
+	padding := 10 * len(valueWords) % 16
+	if padding > 8 {
+		return nil, fmt.Errorf("%w: invalid length", ErrInvalidShare)
+	}
+	v := new(big.Int)
+	for _, w := range valueWords {
+		v.Lsh(v, 10).Or(v, big.NewInt(int64(w)))
+	}
+	n := (10*len(valueWords) - padding) / 8
+	if v.BitLen() > 8*n {
+		return nil, fmt.Errorf("%w: non-zero padding", ErrInvalidShare)
+	}
+	s.Value = v.FillBytes(make([]byte, n))
+	return s, nil
+}
+
+// SharesComplete reports whether shares hold enough groups and members to
+// recover the secret
+func SharesComplete(shares []*Share) bool {
+	if len(shares) == 0 {
+		return false
+	}
+	return len(completeGroups(shares)) >= shares[0].GroupThreshold
+}
+
+// CombineShares recovers the master secret from SLIP-39 shares, decrypting
+// it with passphrase (empty if none was set when the shares were created)
+func CombineShares(shares []*Share, passphrase []byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shares given")
+	}
+	for _, c := range passphrase {
+		if c < 32 || c > 126 {
+			return nil, errors.New("SLIP-39 passphrase must be printable ASCII")
+		}
+	}
+	first := shares[0]
+	for _, s := range shares[1:] {
+		if s.ID != first.ID || s.Extendable != first.Extendable || s.Exponent != first.Exponent {
+			return nil, errors.New("shares belong to different secrets")
+		}
+		if s.GroupThreshold != first.GroupThreshold || s.GroupCount != first.GroupCount || len(s.Value) != len(first.Value) {
+			return nil, errors.New("shares have inconsistent group parameters")
+		}
+	}
+	if len(first.Value) < 16 || len(first.Value)%2 != 0 {
+		return nil, fmt.Errorf("%w: secret length %d", ErrInvalidShare, len(first.Value))
+	}
+
+	groups := completeGroups(shares)
+	if len(groups) < first.GroupThreshold {
+		return nil, fmt.Errorf("need %d complete groups, have %d", first.GroupThreshold, len(groups))
+	}
+	groupSecrets := make([]sharePoint, 0, first.GroupThreshold)
+	for _, members := range groups[:first.GroupThreshold] {
+		secret, err := recoverSecret(members[0].MemberThreshold, members)
+		if err != nil {
+			return nil, fmt.Errorf("group %d: %w", members[0].GroupIndex+1, err)
+		}
+		groupSecrets = append(groupSecrets, sharePoint{x: byte(members[0].GroupIndex), y: secret})
+	}
+
+	encrypted, err := recoverPoints(first.GroupThreshold, groupSecrets)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(encrypted)
+	return slip39Decrypt(encrypted, passphrase, first.Exponent, first.ID, first.Extendable), nil
+}
+
+// ShareGroup is the member threshold and count of a SLIP-39 group
+type ShareGroup struct {
+	Threshold int
+	Count     int
+}
+
+// SplitSecret splits a master secret into extendable SLIP-39 shares,
+// encrypted with passphrase (empty for none). groupThreshold of the groups
+// are needed to recover the secret, each with its member threshold of
+// shares. The shares are returned per group.
+func SplitSecret(secret, passphrase []byte, groupThreshold int, groups []ShareGroup) ([][]string, error) {
+	if len(secret) < 16 || len(secret)%2 != 0 {
+		return nil, fmt.Errorf("master secret must be an even number of bytes, at least 16, got %d", len(secret))
+	}
+	for _, c := range passphrase {
+		if c < 32 || c > 126 {
+			return nil, errors.New("SLIP-39 passphrase must be printable ASCII")
+		}
+	}
+	if len(groups) == 0 || len(groups) > slip39MaxShares {
+		return nil, fmt.Errorf("need 1 to %d groups, got %d", slip39MaxShares, len(groups))
+	}
+	if groupThreshold < 1 || groupThreshold > len(groups) {
+		return nil, fmt.Errorf("group threshold %d must be between 1 and the %d groups", groupThreshold, len(groups))
+	}
+	for i, g := range groups {
+		if g.Count < 1 || g.Count > slip39MaxShares || g.Threshold < 1 || g.Threshold > g.Count {
+			return nil, fmt.Errorf("group %d: %d-of-%d is not a valid threshold and count", i+1, g.Threshold, g.Count)
+		}
+		// A 1-of-n group would hold n copies of the same share
+		if g.Threshold == 1 && g.Count > 1 {
+			return nil, fmt.Errorf("group %d: 1-of-%d is not allowed, use 1-of-1", i+1, g.Count)
+		}
+	}
+
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, err
+	}
+	id := (int(idBytes[0])<<8 | int(idBytes[1])) & 0x7fff
+
+	encrypted := slip39Encrypt(secret, passphrase, slip39Exponent, id, true)
+	defer zero(encrypted)
+	groupSecrets, err := splitPoints(groupThreshold, len(groups), encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([][]string, len(groups))
+	for i, g := range groups {
+		members, err := splitPoints(g.Threshold, g.Count, groupSecrets[i].y)
+		zero(groupSecrets[i].y)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			out[i] = append(out[i], encodeShare(&Share{
+				ID:              id,
+				Extendable:      true,
+				Exponent:        slip39Exponent,
+				GroupIndex:      i,
+				GroupThreshold:  groupThreshold,
+				GroupCount:      len(groups),
+				MemberIndex:     int(m.x),
+				MemberThreshold: g.Threshold,
+				Value:           m.y,
+			}))
+			zero(m.y)
+		}
+	}
+	return out, nil
+}
+
+// splitPoints splits secret into count points of which threshold recover
+// it: threshold-2 random points, the digest at index 254 and the secret at
+// index 255 define the polynomial the remaining points are taken from
+func splitPoints(threshold, count int, secret []byte) ([]sharePoint, error) {
+	if threshold == 1 {
+		points := make([]sharePoint, count)
+		for i := range points {
+			points[i] = sharePoint{x: byte(i), y: append([]byte(nil), secret...)}
+		}
+		return points, nil
+	}
+	points := make([]sharePoint, 0, count)
+	for i := 0; i < threshold-2; i++ {
+		y := make([]byte, len(secret))
+		if _, err := rand.Read(y); err != nil {
+			return nil, err
+		}
+		points = append(points, sharePoint{x: byte(i), y: y})
+	}
+	digest := make([]byte, len(secret))
+	if _, err := rand.Read(digest[slip39DigestLen:]); err != nil {
+		return nil, err
+	}
+	defer zero(digest)
+	mac := hmac.New(sha256.New, digest[slip39DigestLen:])
+	mac.Write(secret)
+	copy(digest, mac.Sum(nil)[:slip39DigestLen])
+
+	base := append(points[:len(points):len(points)],
+		sharePoint{x: slip39DigestIndex, y: digest},
+		sharePoint{x: slip39SecretIndex, y: secret})
+	for i := threshold - 2; i < count; i++ {
+		points = append(points, sharePoint{x: byte(i), y: interpolate(base, byte(i))})
+	}
+	return points, nil
+}
+
+// encodeShare returns the words of a share, with its checksum
+func encodeShare(s *Share) string {
+	idExp := s.ID<<5 | s.Exponent
+	if s.Extendable {
+		idExp |= 1 << 4
+	}
+	params := s.GroupIndex<<16 | (s.GroupThreshold-1)<<12 | (s.GroupCount-1)<<8 | s.MemberIndex<<4 | (s.MemberThreshold - 1)
+	values := []int{idExp >> 10, idExp & 0x3ff, params >> 10, params & 0x3ff}
+
+	// The value is left-padded with zero bits to whole words
+	v := new(big.Int).SetBytes(s.Value)
+	n := (8*len(s.Value) + 9) / 10
+	for i := n - 1; i >= 0; i-- {
+		values = append(values, int(new(big.Int).Rsh(v, uint(10*i)).Int64()&0x3ff))
+	}
+
+	chk := rs1024Polymod(customization(s.Extendable), append(values, 0, 0, 0)) ^ 1
+	values = append(values, chk>>20&0x3ff, chk>>10&0x3ff, chk&0x3ff)
+	words := make([]string, len(values))
+	for i, w := range values {
+		words[i] = slip39Words[w]
+	}
+	return strings.Join(words, " ")
+}
+
+// completeGroups returns the members of each group with at least its member
+// threshold of distinct shares, in order of first appearance
+func completeGroups(shares []*Share) [][]*Share {
+	byGroup := map[int][]*Share{}
+	var order []int
+	for _, s := range shares {
+		members := byGroup[s.GroupIndex]
+		if len(members) == 0 {
+			order = append(order, s.GroupIndex)
+		}
+		dup := false
+		for _, m := range members {
+			dup = dup || m.MemberIndex == s.MemberIndex
+		}
+		if !dup {
+			byGroup[s.GroupIndex] = append(members, s)
+		}
+	}
+	var complete [][]*Share
+	for _, g := range order {
+		if members := byGroup[g]; len(members) >= members[0].MemberThreshold {
+			complete = append(complete, members)
+		}
+	}
+	return complete
+}
+
+type sharePoint struct {
+	x byte
+	y []byte
+}
+
+func recoverSecret(threshold int, members []*Share) ([]byte, error) {
+	points := make([]sharePoint, 0, threshold)
+	for _, m := range members[:threshold] {
+		if m.MemberThreshold != threshold {
+			return nil, errors.New("shares have inconsistent member thresholds")
+		}
+		points = append(points, sharePoint{x: byte(m.MemberIndex), y: m.Value})
+	}
+	return recoverPoints(threshold, points)
+}
+
+// recoverPoints interpolates the secret at index 255 and checks it against
+// the digest stored at index 254
+func recoverPoints(threshold int, points []sharePoint) ([]byte, error) {
+	if threshold == 1 {
+		return append([]byte(nil), points[0].y...), nil
+	}
+	secret := interpolate(points, slip39SecretIndex)
+	digest := interpolate(points, slip39DigestIndex)
+	defer zero(digest)
+	mac := hmac.New(sha256.New, digest[slip39DigestLen:])
+	mac.Write(secret)
+	if !hmac.Equal(mac.Sum(nil)[:slip39DigestLen], digest[:slip39DigestLen]) {
+		zero(secret)
+		return nil, errors.New("share digest mismatch; the shares do not belong together")
+	}
+	return secret, nil
+}
+
+// interpolate evaluates at x the polynomial through points over GF(256)
+func interpolate(points []sharePoint, x byte) []byte {
+	out := make([]byte, len(points[0].y))
+	for _, p := range points {
+		if p.x == x {
+			copy(out, p.y)
+			return out
+		}
+	}
+	for i, p := range points {
+		// Lagrange basis: prod (x - xj) / (xi - xj) over j != i
+		logBasis := 0
+		for j, q := range points {
+			if j != i {
+				logBasis += int(gfLog[q.x^x]) - int(gfLog[p.x^q.x])
+			}
+		}
+		logBasis = (logBasis%255 + 255) % 255
+		for k, v := range p.y {
+			if v != 0 {
+				out[k] ^= gfExp[(int(gfLog[v])+logBasis)%255]
+			}
+		}
+	}
+	return out
+}
+
+// slip39Encrypt runs the master secret through the four round Feistel
+// network
+func slip39Encrypt(secret, passphrase []byte, exponent, id int, extendable bool) []byte {
+	return slip39Feistel(secret, passphrase, exponent, id, extendable, []int{0, 1, 2, 3})
+}
+
+// slip39Decrypt runs the four round Feistel network in reverse
+func slip39Decrypt(encrypted, passphrase []byte, exponent, id int, extendable bool) []byte {
+	return slip39Feistel(encrypted, passphrase, exponent, id, extendable, []int{3, 2, 1, 0})
+}
+
+func slip39Feistel(in, passphrase []byte, exponent, id int, extendable bool, rounds []int) []byte {
+	half := len(in) / 2
+	l := append([]byte(nil), in[:half]...)
+	r := append([]byte(nil), in[half:]...)
+	var salt []byte
+	if !extendable {
+		salt = append([]byte("shamir"), byte(id>>8), byte(id))
+	}
+	iterations := (slip39BaseIterations << uint(exponent)) / slip39Rounds
+	for _, i := range rounds {
+		password := append([]byte{byte(i)}, passphrase...)
+		f := pbkdf2.Key(password, append(append([]byte(nil), salt...), r...), iterations, len(r), sha256.New)
+		zero(password)
+		for k := range l {
+			l[k] ^= f[k]
+		}
+		l, r = r, l
+	}
+	return append(r, l...)
+}
+
+func customization(extendable bool) string {
+	if extendable {
+		return "shamir_extendable"
+	}
+	return "shamir"
+}
+
+var rs1024Gen = [10]int{
+	0xe0e040, 0x1c1c080, 0x3838100, 0x7070200, 0xe0e0009,
+	0x1c0c2412, 0x38086c24, 0x3090fc48, 0x21b1f890, 0x3f3f120,
+}
+
+func rs1024Polymod(custom string, values []int) int {
+	chk := 1
+	step := func(v int) {
+		b := chk >> 20
+		chk = (chk&0xfffff)<<10 ^ v
+		for i := 0; i < 10; i++ {
+			if b>>i&1 == 1 {
+				chk ^= rs1024Gen[i]
+			}
+		}
+	}
+	for _, c := range []byte(custom) {
+		step(int(c))
+	}
+	for _, v := range values {
+		step(v)
+	}
+	return chk
+}
+
+// GF(256) with the Rijndael polynomial, generator 3
+var (
+	gfExp       [255]byte
+	gfLog       [256]byte
+	slip39Index = map[string]int{}
+)
+
+func init() {
+	poly := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(poly)
+		gfLog[poly] = byte(i)
+		poly = poly<<1 ^ poly
+		if poly&0x100 != 0 {
+			poly ^= 0x11b
+		}
+	}
+	for i, w := range slip39Words {
+		slip39Index[w] = i
+	}
+}
+
+// MnemonicFromShamirSecret returns the BIP39 mnemonic held in a master
+// secret recovered from shares, in scaller's encoding (ShamirSecretPrefix
+// followed by the BIP39 entropy)
+func MnemonicFromShamirSecret(secret []byte) (string, error) {
+	if !strings.HasPrefix(string(secret), ShamirSecretPrefix) {
+		return "", fmt.Errorf("recovered secret is not %q followed by BIP39 entropy; shares of SLIP-39 wallets hold a BIP32 seed and are not supported", ShamirSecretPrefix)
+	}
+	return MnemonicFromEntropy(secret[len(ShamirSecretPrefix):])
+}
+
+// ShamirSecretFromMnemonic returns the master secret scaller splits a BIP39
+// mnemonic into: ShamirSecretPrefix followed by the mnemonic's entropy
+func ShamirSecretFromMnemonic(mnemonic string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	// Each word holds 11 bits; the last words/3 bits are the checksum
+	words := strings.Fields(NormalizeMnemonic(mnemonic))
+	v := new(big.Int)
+	for _, w := range words {
+		v.Lsh(v, 11).Or(v, big.NewInt(int64(bip39.ReverseWordMap[w])))
+	}
+	v.Rsh(v, uint(len(words)/3))
+	secret := append([]byte(ShamirSecretPrefix), make([]byte, len(words)*4/3)...)
+	v.FillBytes(secret[len(ShamirSecretPrefix):])
+	return secret, nil
+}
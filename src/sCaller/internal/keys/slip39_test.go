@@ -0,0 +1,179 @@
+package keys
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Official SLIP-39 test vectors (passphrase "TREZOR")
+var slip39Vectors = []struct {
+	name   string
+	shares []string
+	secret string // empty if the shares must be refused
+}{
+	{
+		"valid share, 128 bits",
+		[]string{"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard"},
+		"bb54aac4b89dc868ba37d9cc21b2cece",
+	},
+	{
+		"invalid checksum",
+		[]string{"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision kidney"},
+		"",
+	},
+	{
+		"invalid padding",
+		[]string{"duckling enlarge academic academic email result length solution fridge kidney coal piece deal husband erode duke ajar music cargo fitness"},
+		"",
+	},
+	{
+		"basic sharing 2-of-3",
+		[]string{
+			"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+			"shadow pistol academic acid actress prayer class unknown daughter sweater depict flip twice unkind craft early superior advocate guest smoking",
+		},
+		"b43ceb7e57a0ea8766221624d01b0864",
+	},
+	{
+		"insufficient members",
+		[]string{"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed"},
+		"",
+	},
+	{
+		"insufficient groups",
+		[]string{"eraser senior beard romp adorn nuclear spill corner cradle style ancient family general leader ambition exchange unusual garlic promise voice"},
+		"",
+	},
+	{
+		"valid share, 256 bits",
+		[]string{"theory painting academic academic armed sweater year military elder discuss acne wildlife boring employer fused large satoshi bundle carbon diagnose anatomy hamster leaves tracks paces beyond phantom capital marvel lips brave detect luck"},
+		"989baf9dcaad5b10ca33dfd8cc75e42477025dce88ae83e75a230086a0e00e92",
+	},
+	{
+		"valid extendable share",
+		[]string{"testify swimming academic academic column loyalty smear include exotic bedroom exotic wrist lobe cover grief golden smart junior estimate learn"},
+		"1679b4516e0ee5954351d288a838f45e",
+	},
+}
+
+func TestSLIP39Vectors(t *testing.T) {
+	for _, v := range slip39Vectors {
+		secret, err := combineMnemonics(v.shares, []byte("TREZOR"))
+		if v.secret == "" {
+			if err == nil {
+				t.Errorf("%s: recovered %x, want an error", v.name, secret)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+		if got := hex.EncodeToString(secret); got != v.secret {
+			t.Errorf("%s: secret %s, want %s", v.name, got, v.secret)
+		}
+	}
+}
+
+func TestParseShareErrors(t *testing.T) {
+	for _, v := range slip39Vectors[1:3] {
+		if _, err := ParseShare(v.shares[0]); !errors.Is(err, ErrInvalidShare) {
+			t.Errorf("%s: error %v, want ErrInvalidShare", v.name, err)
+		}
+	}
+}
+
+func TestEncodeShareMatchesVectors(t *testing.T) {
+	for _, v := range slip39Vectors {
+		if v.secret == "" {
+			continue
+		}
+		for _, mnemonic := range v.shares {
+			s, err := ParseShare(mnemonic)
+			if err != nil {
+				t.Fatalf("%s: %v", v.name, err)
+			}
+			if got := encodeShare(s); got != mnemonic {
+				t.Errorf("%s: encoded %q, want %q", v.name, got, mnemonic)
+			}
+		}
+	}
+}
+
+func TestSplitSecretRoundTrip(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	secret, err := ShamirSecretFromMnemonic(mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := SplitSecret(secret, []byte("TREZOR"), 2, []ShareGroup{{2, 3}, {1, 1}, {3, 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 3 || len(groups[0]) != 3 || len(groups[1]) != 1 || len(groups[2]) != 5 {
+		t.Fatalf("got groups of %d, %d and %d shares", len(groups[0]), len(groups[1]), len(groups[2]))
+	}
+
+	// Any two complete groups recover the mnemonic
+	for _, shares := range [][]string{
+		{groups[0][2], groups[1][0], groups[0][0]},
+		{groups[2][4], groups[2][1], groups[0][1], groups[0][0], groups[2][3]},
+		{groups[1][0], groups[2][0], groups[2][2], groups[2][3]},
+	} {
+		got, err := combineMnemonics(shares, []byte("TREZOR"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		recovered, err := MnemonicFromShamirSecret(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recovered != mnemonic {
+			t.Errorf("recovered %q, want %q", recovered, mnemonic)
+		}
+	}
+
+	if _, err := combineMnemonics([]string{groups[0][0], groups[0][1], groups[2][0], groups[2][1]}, []byte("TREZOR")); err == nil {
+		t.Error("one complete group recovered the secret")
+	}
+	// A wrong passphrase decrypts to another secret, which lacks the prefix
+	got, err := combineMnemonics([]string{groups[1][0], groups[0][0], groups[0][1]}, []byte("WRONG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MnemonicFromShamirSecret(got); err == nil {
+		t.Error("wrong passphrase recovered a mnemonic")
+	}
+}
+
+func TestSplitSecretRejectsInvalidGroups(t *testing.T) {
+	secret := []byte(ShamirSecretPrefix + "0123456789abcdef")
+	for _, tt := range []struct {
+		threshold int
+		groups    []ShareGroup
+	}{
+		{1, nil},
+		{2, []ShareGroup{{2, 3}}},
+		{1, []ShareGroup{{1, 3}}},
+		{1, []ShareGroup{{4, 3}}},
+		{1, []ShareGroup{{2, 17}}},
+	} {
+		if _, err := SplitSecret(secret, nil, tt.threshold, tt.groups); err == nil {
+			t.Errorf("SplitSecret(%d, %v) succeeded", tt.threshold, tt.groups)
+		}
+	}
+}
+
+func combineMnemonics(mnemonics []string, passphrase []byte) ([]byte, error) {
+	var shares []*Share
+	for _, m := range mnemonics {
+		s, err := ParseShare(strings.TrimSpace(m))
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return CombineShares(shares, passphrase)
+}
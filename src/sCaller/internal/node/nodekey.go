@@ -0,0 +1,41 @@
+package node
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// nodeKeyFile represents config/node_key.json
+type nodeKeyFile struct {
+	PrivKey struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"priv_key"`
+}
+
+// ID returns the P2P node ID derived from home/config/node_key.json
+func ID(home string) (string, error) {
+	path := filepath.Join(home, "config", "node_key.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var key nodeKeyFile
+	if err := json.Unmarshal(data, &key); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	priv, err := base64.StdEncoding.DecodeString(key.PrivKey.Value)
+	if err != nil || len(priv) != 64 {
+		return "", fmt.Errorf("invalid ed25519 key in %s", path)
+	}
+
+	// Node ID is the hex address: first 20 bytes of sha256(pubkey)
+	sum := sha256.Sum256(priv[32:])
+	return hex.EncodeToString(sum[:20]), nil
+}
@@ -0,0 +1,201 @@
+package node
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Process describes a running sekaid instance found in /proc
+type Process struct {
+	PID  int
+	PPID int
+	Args []string
+}
+
+// StateDirName is the directory under the sekaid home where scaller keeps its own state
+const StateDirName = ".scaller"
+
+// StateDir returns the directory where scaller keeps its own state for home
+func StateDir(home string) string {
+	return filepath.Join(home, StateDirName)
+}
+
+// PausePath returns the marker file that holds the restart loop while present
+func PausePath(home string) string {
+	return filepath.Join(StateDir(home), "pause")
+}
+
+// Pause creates the pause marker so a supervising `scaller start --restart`
+// does not restart sekaid while it is stopped on purpose
+func Pause(home string) error {
+	if err := os.MkdirAll(StateDir(home), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(PausePath(home), []byte(strconv.Itoa(os.Getpid())+"\n"), 0600)
+}
+
+// Resume removes the pause marker
+func Resume(home string) error {
+	if err := os.Remove(PausePath(home)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// IsPaused reports whether the pause marker exists
+func IsPaused(home string) bool {
+	_, err := os.Stat(PausePath(home))
+	return err == nil
+}
+
+// FindSekaid returns the running `sekaid start` process using home, or nil
+func FindSekaid(home string) (*Process, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read /proc: %w", err)
+	}
+
+	home = filepath.Clean(home)
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		args := readCmdline(pid)
+		if len(args) < 2 || filepath.Base(args[0]) != "sekaid" || args[1] != "start" {
+			continue
+		}
+		if homeArg(args) != home {
+			continue
+		}
+		return &Process{PID: pid, PPID: readPPID(pid), Args: args}, nil
+	}
+	return nil, nil
+}
+
+// Supervised reports whether the process was started by `scaller start --restart`
+// and will therefore be restarted by its parent once the pause marker is removed
+func (p *Process) Supervised() bool {
+	args := readCmdline(p.PPID)
+	if len(args) < 2 || filepath.Base(args[0]) != "scaller" || args[1] != "start" {
+		return false
+	}
+	for _, a := range args[2:] {
+		if a == "--restart" || strings.HasPrefix(a, "--restart=") {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop sends SIGTERM and waits for the process to exit, escalating to SIGKILL
+// after timeout
+func (p *Process) Stop(timeout time.Duration) error {
+	if err := syscall.Kill(p.PID, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to signal pid %d: %w", p.PID, err)
+	}
+	if waitExit(p.PID, timeout) {
+		return nil
+	}
+	if err := syscall.Kill(p.PID, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return fmt.Errorf("failed to kill pid %d: %w", p.PID, err)
+	}
+	if !waitExit(p.PID, 10*time.Second) {
+		return fmt.Errorf("pid %d did not exit", p.PID)
+	}
+	return nil
+}
+
+// StartDetached runs sekaidPath with args (e.g. the Args of a stopped
+// Process without the program name) in its own session with output appended
+// to logPath, for nodes that were not running under a supervisor
+func StartDetached(sekaidPath string, args []string, logPath string) (int, error) {
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", logPath, err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(sekaidPath, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	pid := cmd.Process.Pid
+	cmd.Process.Release()
+	return pid, nil
+}
+
+func waitExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !alive(pid) {
+			return true
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return !alive(pid)
+}
+
+// alive treats zombies as exited, since their parent may not reap them promptly
+func alive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	fields := statFields(string(stat))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+func readCmdline(pid int) []string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+}
+
+func readPPID(pid int) int {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0
+	}
+	fields := statFields(string(stat))
+	if len(fields) < 2 {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	return ppid
+}
+
+// statFields returns the /proc/<pid>/stat fields following the command name
+func statFields(stat string) []string {
+	idx := strings.LastIndex(stat, ")")
+	if idx == -1 {
+		return nil
+	}
+	return strings.Fields(stat[idx+1:])
+}
+
+func homeArg(args []string) string {
+	for i, a := range args {
+		if a == "--home" && i+1 < len(args) {
+			return filepath.Clean(args[i+1])
+		}
+		if strings.HasPrefix(a, "--home=") {
+			return filepath.Clean(strings.TrimPrefix(a, "--home="))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package node
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SignerConnections counts the established connections on sekaid's
+// priv_validator_laddr, read from /proc/net of the caller's network
+// namespace. The signer dials sekaid, so its connections have the laddr as
+// their local end.
+func SignerConnections(laddr string) (int, error) {
+	u, err := url.Parse(laddr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid priv_validator_laddr %q: %w", laddr, err)
+	}
+	switch u.Scheme {
+	case "tcp":
+		port, err := strconv.ParseUint(u.Port(), 10, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid priv_validator_laddr %q: no port", laddr)
+		}
+		n := 0
+		for _, file := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+			c, err := countProcNet(file, func(f []string) bool {
+				// local_address is hex ip:port, st 01 is ESTABLISHED
+				i := strings.LastIndexByte(f[1], ':')
+				p, _ := strconv.ParseUint(f[1][i+1:], 16, 16)
+				return p == port && f[3] == "01"
+			})
+			if err != nil && !os.IsNotExist(err) {
+				return 0, err
+			}
+			n += c
+		}
+		return n, nil
+	case "unix":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		// Accepted sockets share the listener's path; st 03 is connected
+		return countProcNet("/proc/net/unix", func(f []string) bool {
+			return len(f) > 7 && f[5] == "03" && f[7] == path
+		})
+	}
+	return 0, fmt.Errorf("unsupported priv_validator_laddr scheme %q", u.Scheme)
+}
+
+// countProcNet counts the entries of a /proc/net table matching match
+func countProcNet(file string, match func(fields []string) bool) (int, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n := 0
+	s := bufio.NewScanner(f)
+	s.Scan() // header
+	for s.Scan() {
+		if fields := strings.Fields(s.Text()); len(fields) > 5 && match(fields) {
+			n++
+		}
+	}
+	return n, s.Err()
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// overlay VPNs use like private addresses
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// PublicSignerAddrs checks a priv_validator_laddr and returns the publicly
+// routable addresses sekaid would accept signer connections on: its own IP,
+// or for an unspecified IP those of the local interfaces. unix sockets are
+// never public.
+func PublicSignerAddrs(laddr string) ([]net.IP, error) {
+	u, err := url.Parse(laddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid priv_validator_laddr %q: %w", laddr, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		if !filepath.IsAbs(path) || u.Host != "" {
+			return nil, fmt.Errorf("invalid priv_validator_laddr %q, expected unix:///absolute/path", laddr)
+		}
+		return nil, nil
+	case "tcp":
+	default:
+		return nil, fmt.Errorf("invalid priv_validator_laddr %q, expected tcp://host:port or unix:///path", laddr)
+	}
+	if _, err := strconv.ParseUint(u.Port(), 10, 16); err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid priv_validator_laddr %q, expected tcp://host:port", laddr)
+	}
+
+	ips := []net.IP{net.ParseIP(u.Hostname())}
+	if ips[0] == nil {
+		if ips, err = net.LookupIP(u.Hostname()); err != nil {
+			return nil, fmt.Errorf("cannot resolve priv_validator_laddr host: %w", err)
+		}
+	}
+	var public []net.IP
+	for _, ip := range ips {
+		if !ip.IsUnspecified() {
+			if isPublicIP(ip) {
+				public = append(public, ip)
+			}
+			continue
+		}
+		// 0.0.0.0 listens on IPv4 interfaces only, :: on all of them
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			n, ok := a.(*net.IPNet)
+			if ok && (ip.To4() == nil || n.IP.To4() != nil) && isPublicIP(n.IP) {
+				public = append(public, n.IP)
+			}
+		}
+	}
+	return public, nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
@@ -0,0 +1,82 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SignState is the last height, round and step a validator key signed at
+type SignState struct {
+	Height int64
+	Round  int64
+	Step   int64
+}
+
+// signStateFile holds priv_validator_state.json or a TMKMS state file: the
+// former writes the height as a string and the round as a number, the
+// latter both as strings
+type signStateFile struct {
+	Height json.RawMessage `json:"height"`
+	Round  json.RawMessage `json:"round"`
+	Step   json.RawMessage `json:"step"`
+}
+
+// SignStatePath returns the path of priv_validator_state.json in home
+func SignStatePath(home string) string {
+	return filepath.Join(home, "data", "priv_validator_state.json")
+}
+
+// LoadSignState reads a signing state file. A missing file is the zero state.
+func LoadSignState(path string) (SignState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return SignState{}, nil
+	}
+	if err != nil {
+		return SignState{}, err
+	}
+	return ParseSignState(data)
+}
+
+// ParseSignState parses priv_validator_state.json or a TMKMS state file
+func ParseSignState(data []byte) (SignState, error) {
+	var f signStateFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return SignState{}, fmt.Errorf("failed to parse signing state: %w", err)
+	}
+	var s SignState
+	for _, field := range []struct {
+		name string
+		raw  json.RawMessage
+		dst  *int64
+	}{{"height", f.Height, &s.Height}, {"round", f.Round, &s.Round}, {"step", f.Step, &s.Step}} {
+		if len(field.raw) == 0 {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.Trim(string(field.raw), `"`), 10, 64)
+		if err != nil {
+			return SignState{}, fmt.Errorf("invalid signing state %s %s", field.name, field.raw)
+		}
+		*field.dst = n
+	}
+	return s, nil
+}
+
+// Less reports whether s is before o in height, round, step order
+func (s SignState) Less(o SignState) bool {
+	if s.Height != o.Height {
+		return s.Height < o.Height
+	}
+	if s.Round != o.Round {
+		return s.Round < o.Round
+	}
+	return s.Step < o.Step
+}
+
+func (s SignState) String() string {
+	return fmt.Sprintf("%d/%d/%d", s.Height, s.Round, s.Step)
+}
@@ -0,0 +1,90 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// JoinState records which join steps completed for a home directory
+type JoinState struct {
+	Steps map[string]time.Time `json:"steps"`
+
+	path string
+}
+
+// LoadJoinState reads the join state for home, returning an empty state if none exists
+func LoadJoinState(home string) (*JoinState, error) {
+	s := &JoinState{
+		Steps: make(map[string]time.Time),
+		path:  filepath.Join(StateDir(home), "join.json"),
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	if s.Steps == nil {
+		s.Steps = make(map[string]time.Time)
+	}
+	return s, nil
+}
+
+// Done reports whether step has completed
+func (s *JoinState) Done(step string) bool {
+	_, ok := s.Steps[step]
+	return ok
+}
+
+// MarkDone records step as completed and persists the state
+func (s *JoinState) MarkDone(step string) error {
+	s.Steps[step] = time.Now().UTC()
+	return s.save()
+}
+
+// Reset forgets all completed steps and persists the state
+func (s *JoinState) Reset() error {
+	s.Steps = make(map[string]time.Time)
+	return s.save()
+}
+
+func (s *JoinState) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+// LockJoin takes an exclusive lock on home for the duration of a join. The
+// lock is released by the returned function or when the process exits.
+func LockJoin(home string) (func(), error) {
+	if err := os.MkdirAll(StateDir(home), 0700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(StateDir(home), "join.lock")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, fmt.Errorf("another join is running on %s", home)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return func() { f.Close() }, nil
+}
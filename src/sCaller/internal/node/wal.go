@@ -0,0 +1,148 @@
+package node
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// walMaxMsgSize bounds a consensus WAL record, as CometBFT does
+const walMaxMsgSize = 1024 * 1024
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// CommittedHeight returns the last height the consensus WAL of a stopped
+// node marks as committed (its last EndHeight record), which is the height
+// of the block store. Heights committed by block sync are not recorded there.
+func CommittedHeight(home string) (int64, error) {
+	dir := filepath.Join(home, "data", "cs.wal")
+	// The head file "wal" is the newest, rotated files wal.NNN older ones
+	files, err := filepath.Glob(filepath.Join(dir, "wal.[0-9]*"))
+	if err != nil {
+		return 0, err
+	}
+	index := func(file string) int {
+		n, _ := strconv.Atoi(filepath.Ext(file)[1:])
+		return n
+	}
+	sort.Slice(files, func(i, j int) bool { return index(files[i]) > index(files[j]) })
+	files = append([]string{filepath.Join(dir, "wal")}, files...)
+
+	for _, file := range files {
+		height, found, err := lastEndHeight(file)
+		if err != nil && !os.IsNotExist(err) {
+			return 0, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if found {
+			return height, nil
+		}
+	}
+	return 0, fmt.Errorf("no committed height recorded in %s", dir)
+}
+
+// lastEndHeight returns the height of the last EndHeight record in a WAL
+// file. A truncated last record, left by a crash, ends the file.
+func lastEndHeight(file string) (height int64, found bool, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+
+	var header [8]byte
+	for {
+		// Each record is a CRC32C and a length, both big endian, then a
+		// TimedWALMessage
+		if _, err := io.ReadFull(f, header[:]); err != nil {
+			return height, found, nil
+		}
+		size := binary.BigEndian.Uint32(header[4:])
+		if size > walMaxMsgSize {
+			return 0, false, fmt.Errorf("WAL record of %d bytes", size)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(f, data); err != nil {
+			return height, found, nil
+		}
+		if crc32.Checksum(data, crc32c) != binary.BigEndian.Uint32(header[:4]) {
+			return 0, false, errors.New("WAL record checksum mismatch")
+		}
+		// TimedWALMessage.msg (2) is a WALMessage, whose end_height (4) is
+		// an EndHeight with the height (1)
+		msg, ok := protoField(data, 2)
+		if !ok {
+			continue
+		}
+		if end, ok := protoField(msg, 4); ok {
+			h, _ := protoVarint(end, 1)
+			height, found = int64(h), true
+		}
+	}
+}
+
+// protoField returns the bytes of the last length-delimited field num of a
+// protobuf message
+func protoField(data []byte, num uint64) (b []byte, ok bool) {
+	walkProto(data, func(n, wire uint64, v uint64, raw []byte) {
+		if n == num && wire == 2 {
+			b, ok = raw, true
+		}
+	})
+	return b, ok
+}
+
+// protoVarint returns the last varint field num of a protobuf message
+func protoVarint(data []byte, num uint64) (v uint64, ok bool) {
+	walkProto(data, func(n, wire uint64, val uint64, raw []byte) {
+		if n == num && wire == 0 {
+			v, ok = val, true
+		}
+	})
+	return v, ok
+}
+
+// walkProto calls fn for each field of a protobuf message, stopping at the
+// first malformed one
+func walkProto(data []byte, fn func(num, wire, v uint64, raw []byte)) {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return
+		}
+		data = data[n:]
+		var v uint64
+		var raw []byte
+		switch wire := key & 7; wire {
+		case 0:
+			if v, n = binary.Uvarint(data); n <= 0 {
+				return
+			}
+			data = data[n:]
+		case 1, 5:
+			size := 8
+			if wire == 5 {
+				size = 4
+			}
+			if len(data) < size {
+				return
+			}
+			data = data[size:]
+		case 2:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return
+			}
+			raw = data[n : n+int(l)]
+			data = data[n+int(l):]
+		default:
+			return
+		}
+		fn(key>>3, key&7, v, raw)
+	}
+}
@@ -0,0 +1,251 @@
+package peers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Peer is a P2P endpoint in nodeID@host:port form
+type Peer struct {
+	ID   string
+	Host string
+	Port string
+}
+
+// String formats the peer as nodeID@host:port
+func (p Peer) String() string {
+	return fmt.Sprintf("%s@%s", p.ID, net.JoinHostPort(p.Host, p.Port))
+}
+
+// Addr returns host:port
+func (p Peer) Addr() string {
+	return net.JoinHostPort(p.Host, p.Port)
+}
+
+// Parse parses a nodeID@host:port string
+func Parse(s string) (Peer, error) {
+	s = strings.TrimSpace(s)
+	id, addr, ok := strings.Cut(s, "@")
+	if !ok || id == "" {
+		return Peer{}, fmt.Errorf("invalid peer %q: expected nodeID@host:port", s)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || port == "" {
+		return Peer{}, fmt.Errorf("invalid peer %q: expected nodeID@host:port", s)
+	}
+	return Peer{ID: id, Host: host, Port: port}, nil
+}
+
+// statusResponse represents the RPC /status fields used to locate the P2P endpoint
+type statusResponse struct {
+	Result struct {
+		NodeInfo struct {
+			ID         string `json:"id"`
+			ListenAddr string `json:"listen_addr"`
+			Network    string `json:"network"`
+			Other      struct {
+				RPCAddress string `json:"rpc_address"`
+			} `json:"other"`
+		} `json:"node_info"`
+	} `json:"result"`
+}
+
+// netInfoResponse represents the RPC /net_info response
+type netInfoResponse struct {
+	Result struct {
+		Peers []struct {
+			NodeInfo struct {
+				ID         string `json:"id"`
+				ListenAddr string `json:"listen_addr"`
+			} `json:"node_info"`
+			RemoteIP string `json:"remote_ip"`
+		} `json:"peers"`
+	} `json:"result"`
+}
+
+// FromStatus derives the P2P endpoint of an RPC node from its /status.
+// The host comes from node_info.listen_addr, then other.rpc_address, then
+// the RPC URL itself, using the first one that is a routable address.
+func FromStatus(rpcNode string) (Peer, error) {
+	base, err := baseURL(rpcNode)
+	if err != nil {
+		return Peer{}, err
+	}
+
+	var status statusResponse
+	if err := getJSON(base.String()+"/status", &status); err != nil {
+		return Peer{}, err
+	}
+
+	info := status.Result.NodeInfo
+	if info.ID == "" {
+		return Peer{}, fmt.Errorf("empty node ID in /status response")
+	}
+
+	listenHost, port, err := splitListenAddr(info.ListenAddr)
+	if err != nil {
+		return Peer{}, fmt.Errorf("invalid listen_addr %q: %w", info.ListenAddr, err)
+	}
+
+	host := base.Hostname()
+	rpcHost, _, _ := splitListenAddr(info.Other.RPCAddress)
+	switch {
+	case routable(listenHost):
+		host = listenHost
+	case routable(rpcHost):
+		host = rpcHost
+	}
+
+	return Peer{ID: info.ID, Host: host, Port: port}, nil
+}
+
+// Network returns the chain ID an RPC node reports in its /status
+func Network(rpcNode string) (string, error) {
+	base, err := baseURL(rpcNode)
+	if err != nil {
+		return "", err
+	}
+
+	var status statusResponse
+	if err := getJSON(base.String()+"/status", &status); err != nil {
+		return "", err
+	}
+	if status.Result.NodeInfo.Network == "" {
+		return "", fmt.Errorf("empty network in /status response")
+	}
+	return status.Result.NodeInfo.Network, nil
+}
+
+// Harvest returns the peers of the RPC node taken from /net_info
+func Harvest(rpcNode string) ([]Peer, error) {
+	base, err := baseURL(rpcNode)
+	if err != nil {
+		return nil, err
+	}
+
+	var netInfo netInfoResponse
+	if err := getJSON(base.String()+"/net_info", &netInfo); err != nil {
+		return nil, err
+	}
+
+	var result []Peer
+	for _, p := range netInfo.Result.Peers {
+		listenHost, port, err := splitListenAddr(p.NodeInfo.ListenAddr)
+		if err != nil || p.NodeInfo.ID == "" {
+			continue
+		}
+		// Prefer the advertised address; fall back to the IP the RPC node sees
+		host := listenHost
+		if !routable(host) || isPrivate(host) {
+			host = p.RemoteIP
+		}
+		if host == "" {
+			continue
+		}
+		result = append(result, Peer{ID: p.NodeInfo.ID, Host: host, Port: port})
+	}
+	return result, nil
+}
+
+// Reachable dials every peer concurrently and splits them into reachable and
+// unreachable, preserving the input order. Errors are keyed by peer string.
+func Reachable(list []Peer, timeout time.Duration) ([]Peer, map[string]error) {
+	errs := make([]error, len(list))
+	var wg sync.WaitGroup
+	for i, p := range list {
+		wg.Add(1)
+		go func(i int, p Peer) {
+			defer wg.Done()
+			conn, err := net.DialTimeout("tcp", p.Addr(), timeout)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			conn.Close()
+		}(i, p)
+	}
+	wg.Wait()
+
+	var ok []Peer
+	failed := make(map[string]error)
+	for i, p := range list {
+		if errs[i] != nil {
+			failed[p.String()] = errs[i]
+			continue
+		}
+		ok = append(ok, p)
+	}
+	return ok, failed
+}
+
+// Join formats peers as a comma-separated list
+func Join(list []Peer) string {
+	parts := make([]string, len(list))
+	for i, p := range list {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func baseURL(rpcNode string) (*url.URL, error) {
+	// Ensure rpcNode has scheme
+	if !strings.HasPrefix(rpcNode, "http") {
+		rpcNode = "http://" + rpcNode
+	}
+	u, err := url.Parse(strings.TrimRight(rpcNode, "/"))
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid RPC address %q", rpcNode)
+	}
+	return u, nil
+}
+
+func getJSON(url string, v interface{}) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with status: %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// splitListenAddr splits "tcp://host:port" into host and port
+func splitListenAddr(addr string) (string, string, error) {
+	if i := strings.Index(addr, "://"); i != -1 {
+		addr = addr[i+3:]
+	}
+	return net.SplitHostPort(addr)
+}
+
+// routable reports whether host can be dialled from another machine
+func routable(host string) bool {
+	if host == "" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host != "localhost"
+	}
+	return !ip.IsUnspecified() && !ip.IsLoopback()
+}
+
+func isPrivate(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsPrivate()
+}
@@ -0,0 +1,220 @@
+package preset
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"scaller/internal/config"
+)
+
+// Performance presets
+const (
+	ValidatorLowLatency = "validator-low-latency"
+	RPCHeavy            = "rpc-heavy"
+	Archive             = "archive"
+	LowMemory           = "low-memory"
+)
+
+// Names lists the supported presets
+var Names = []string{ValidatorLowLatency, RPCHeavy, Archive, LowMemory}
+
+// Pruning modes accepted by app.toml
+var PruningModes = []string{"default", "nothing", "everything", "custom"}
+
+// Defaults for custom pruning when no keep-recent/interval is given
+const (
+	DefaultKeepRecent = 100
+	DefaultInterval   = 10
+)
+
+// Limits enforced by the Cosmos SDK for custom pruning
+const (
+	minKeepRecent = 2
+	minInterval   = 10
+)
+
+// Settings returns the overrides of a preset
+func Settings(name string) (config.ScallConfig, error) {
+	s := make(config.ScallConfig)
+	switch name {
+	case ValidatorLowLatency:
+		// Small state, fast gossip, nothing the validator does not need itself
+		setPruning(s, "custom", DefaultKeepRecent, DefaultInterval)
+		s.SetValue("config.db_backend", "goleveldb")
+		s.SetValue("config.mempool.size", int64(5000))
+		s.SetValue("config.mempool.cache_size", int64(10000))
+		s.SetValue("config.mempool.max_txs_bytes", int64(1073741824))
+		s.SetValue("config.p2p.send_rate", int64(20480000))
+		s.SetValue("config.p2p.recv_rate", int64(20480000))
+		s.SetValue("config.p2p.flush_throttle_timeout", "10ms")
+		s.SetValue("config.tx_index.indexer", "null")
+		s.SetValue("app.iavl-cache-size", int64(781250))
+		s.SetValue("app.state-sync.snapshot-interval", int64(0))
+	case RPCHeavy:
+		// About three weeks of history at 5s blocks for queries, large caches
+		setPruning(s, "custom", 362880, 100)
+		s.SetValue("config.db_backend", "goleveldb")
+		s.SetValue("config.mempool.size", int64(10000))
+		s.SetValue("config.mempool.cache_size", int64(20000))
+		s.SetValue("config.mempool.max_txs_bytes", int64(2147483648))
+		s.SetValue("config.p2p.send_rate", int64(10240000))
+		s.SetValue("config.p2p.recv_rate", int64(10240000))
+		s.SetValue("config.rpc.max_open_connections", int64(2000))
+		s.SetValue("config.tx_index.indexer", "kv")
+		s.SetValue("app.iavl-cache-size", int64(1562500))
+		s.SetValue("app.state-sync.snapshot-interval", int64(1000))
+		s.SetValue("app.state-sync.snapshot-keep-recent", int64(2))
+	case Archive:
+		// Full history; serves state-sync snapshots to the network
+		setPruning(s, "nothing", 0, 0)
+		s.SetValue("config.db_backend", "goleveldb")
+		s.SetValue("config.mempool.size", int64(5000))
+		s.SetValue("config.mempool.cache_size", int64(10000))
+		s.SetValue("config.p2p.send_rate", int64(10240000))
+		s.SetValue("config.p2p.recv_rate", int64(10240000))
+		s.SetValue("config.tx_index.indexer", "kv")
+		s.SetValue("app.iavl-cache-size", int64(1562500))
+		s.SetValue("app.min-retain-blocks", int64(0))
+		s.SetValue("app.state-sync.snapshot-interval", int64(1000))
+		s.SetValue("app.state-sync.snapshot-keep-recent", int64(5))
+	case LowMemory:
+		// Keep as little in memory and on disk as a working node can
+		setPruning(s, "everything", 0, 0)
+		s.SetValue("config.db_backend", "goleveldb")
+		s.SetValue("config.mempool.size", int64(1000))
+		s.SetValue("config.mempool.cache_size", int64(2000))
+		s.SetValue("config.mempool.max_txs_bytes", int64(268435456))
+		s.SetValue("config.p2p.max_num_inbound_peers", int64(20))
+		s.SetValue("config.p2p.max_num_outbound_peers", int64(5))
+		s.SetValue("config.tx_index.indexer", "null")
+		s.SetValue("app.iavl-cache-size", int64(100000))
+		s.SetValue("app.iavl-disable-fastnode", true)
+		s.SetValue("app.state-sync.snapshot-interval", int64(0))
+	default:
+		return nil, fmt.Errorf("unknown preset %q (use %s)", name, strings.Join(Names, "|"))
+	}
+	return s, nil
+}
+
+// Pruning returns the overrides for a pruning mode. keepRecent and interval
+// apply to custom only; zero selects the defaults (100 and 10).
+func Pruning(mode string, keepRecent, interval uint64) (config.ScallConfig, error) {
+	if !contains(PruningModes, mode) {
+		return nil, fmt.Errorf("unknown pruning mode %q (use %s)", mode, strings.Join(PruningModes, "|"))
+	}
+	if mode != "custom" && (keepRecent != 0 || interval != 0) {
+		return nil, fmt.Errorf("--prune-keep-recent and --prune-interval require --prune custom")
+	}
+	if mode == "custom" {
+		if keepRecent == 0 {
+			keepRecent = DefaultKeepRecent
+		}
+		if interval == 0 {
+			interval = DefaultInterval
+		}
+	}
+
+	s := make(config.ScallConfig)
+	setPruning(s, mode, keepRecent, interval)
+	if _, err := Check(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func setPruning(s config.ScallConfig, mode string, keepRecent, interval uint64) {
+	s.SetValue("app.pruning", mode)
+	if mode == "custom" {
+		s.SetValue("app.pruning-keep-recent", strconv.FormatUint(keepRecent, 10))
+		s.SetValue("app.pruning-interval", strconv.FormatUint(interval, 10))
+	}
+}
+
+// Conflicts returns the keys that a and b both set to different values
+func Conflicts(a, b config.ScallConfig) []string {
+	var keys []string
+	for k, v := range a {
+		if w, ok := b[k]; ok && config.FormatValue(v) != config.FormatValue(w) {
+			keys = append(keys, fmt.Sprintf("%s (%s vs %s)", k, config.FormatValue(v), config.FormatValue(w)))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Check validates combinations of settings in s that sekaid would reject or
+// that defeat each other. Keys not in s are not checked.
+func Check(s config.ScallConfig) ([]string, error) {
+	var warnings, problems []string
+
+	pruning, _ := s["app.pruning"].(string)
+	keepRecent, hasKeep := uintValue(s["app.pruning-keep-recent"])
+	interval, hasInterval := uintValue(s["app.pruning-interval"])
+
+	if pruning == "custom" {
+		if hasKeep && keepRecent < minKeepRecent {
+			problems = append(problems, fmt.Sprintf("app.pruning-keep-recent must be at least %d for custom pruning, got %d", minKeepRecent, keepRecent))
+		}
+		if hasInterval && interval < minInterval {
+			problems = append(problems, fmt.Sprintf("app.pruning-interval must be at least %d for custom pruning, got %d", minInterval, interval))
+		}
+	} else if pruning != "" && (hasKeep || hasInterval) {
+		warnings = append(warnings, fmt.Sprintf("app.pruning-keep-recent and app.pruning-interval are ignored with app.pruning = %q", pruning))
+	}
+
+	if pruning == "nothing" && s["config.statesync.enable"] == true {
+		problems = append(problems, "app.pruning = \"nothing\" keeps full history, which state sync cannot provide; disable statesync for archive nodes")
+	}
+
+	if maxTx, ok := intValue(s["config.mempool.max_tx_bytes"]); ok {
+		if maxTxs, ok := intValue(s["config.mempool.max_txs_bytes"]); ok && maxTx > maxTxs {
+			problems = append(problems, fmt.Sprintf("config.mempool.max_tx_bytes (%d) exceeds config.mempool.max_txs_bytes (%d)", maxTx, maxTxs))
+		}
+	}
+	if size, ok := intValue(s["config.mempool.size"]); ok {
+		if cache, ok := intValue(s["config.mempool.cache_size"]); ok && cache > 0 && cache < size {
+			warnings = append(warnings, fmt.Sprintf("config.mempool.cache_size (%d) is smaller than config.mempool.size (%d)", cache, size))
+		}
+	}
+
+	if s["config.tx_index.indexer"] == "null" && s["app.api.enable"] == true {
+		warnings = append(warnings, "config.tx_index.indexer = \"null\" disables tx queries served by the enabled API")
+	}
+
+	if len(problems) > 0 {
+		return warnings, fmt.Errorf("invalid setting combination:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return warnings, nil
+}
+
+func uintValue(v interface{}) (uint64, bool) {
+	switch x := v.(type) {
+	case string:
+		n, err := strconv.ParseUint(x, 10, 64)
+		return n, err == nil
+	case int64:
+		return uint64(x), x >= 0
+	}
+	return 0, false
+}
+
+func intValue(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return x, true
+	case int:
+		return int64(x), true
+	}
+	return 0, false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package privval
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// SignedMsgType values of tendermint.types
+const (
+	typePrevote   = 1
+	typePrecommit = 2
+	typeProposal  = 32
+)
+
+// Steps recorded in the signing state, as in priv_validator_state.json
+const (
+	stepPropose   = 1
+	stepPrevote   = 2
+	stepPrecommit = 3
+)
+
+// signable is a decoded vote or proposal with the fields sign bytes need
+type signable struct {
+	msg       message
+	msgType   uint64
+	height    int64
+	round     int64
+	polRound  int64
+	blockID   []byte // raw tendermint.types.BlockID
+	timestamp []byte // raw google.protobuf.Timestamp
+	proposal  bool
+}
+
+// Field numbers of the timestamp and signature in Vote and Proposal
+func (s *signable) timestampField() int {
+	if s.proposal {
+		return 6
+	}
+	return 5
+}
+
+func (s *signable) signatureField() int {
+	if s.proposal {
+		return 7
+	}
+	return 8
+}
+
+// parseVote decodes a tendermint.types.Vote
+func parseVote(data []byte) (*signable, error) {
+	m, err := parseMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	s := &signable{
+		msg:       m,
+		msgType:   m.uint(1),
+		height:    m.int(2),
+		round:     int64(int32(m.uint(3))),
+		blockID:   m.bytes(4),
+		timestamp: m.bytes(5),
+	}
+	if s.msgType != typePrevote && s.msgType != typePrecommit {
+		return nil, fmt.Errorf("unexpected vote type %d", s.msgType)
+	}
+	return s, nil
+}
+
+// parseProposal decodes a tendermint.types.Proposal
+func parseProposal(data []byte) (*signable, error) {
+	m, err := parseMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	s := &signable{
+		msg:       m,
+		msgType:   m.uint(1),
+		height:    m.int(2),
+		round:     int64(int32(m.uint(3))),
+		polRound:  int64(int32(m.uint(4))),
+		blockID:   m.bytes(5),
+		timestamp: m.bytes(6),
+		proposal:  true,
+	}
+	if s.msgType != typeProposal {
+		return nil, fmt.Errorf("unexpected proposal type %d", s.msgType)
+	}
+	return s, nil
+}
+
+func (s *signable) step() int64 {
+	switch s.msgType {
+	case typePrevote:
+		return stepPrevote
+	case typePrecommit:
+		return stepPrecommit
+	}
+	return stepPropose
+}
+
+func (s *signable) String() string {
+	name := map[uint64]string{typePrevote: "prevote", typePrecommit: "precommit", typeProposal: "proposal"}[s.msgType]
+	return fmt.Sprintf("%s %d/%d/%d", name, s.height, s.round, s.step())
+}
+
+// signBytes returns the length-delimited CanonicalVote or CanonicalProposal
+// for chainID, with the given raw timestamp
+func (s *signable) signBytes(chainID string, timestamp []byte) ([]byte, error) {
+	bid, err := canonicalBlockID(s.blockID)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := canonicalTimestamp(timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	b := appendVarint(nil, 1, s.msgType)
+	b = appendSfixed64(b, 2, s.height)
+	b = appendSfixed64(b, 3, s.round)
+	next := 4
+	if s.proposal {
+		b = appendVarint(b, 4, uint64(s.polRound))
+		next = 5
+	}
+	if bid != nil {
+		b = appendEmbedded(b, next, bid)
+	}
+	b = appendEmbedded(b, next+1, ts)
+	b = appendBytes(b, next+2, []byte(chainID))
+	return delimited(b), nil
+}
+
+// canonicalBlockID re-encodes a BlockID as CanonicalBlockID, or nil for the
+// zero block ID of a nil vote
+func canonicalBlockID(raw []byte) ([]byte, error) {
+	m, err := parseMessage(raw)
+	if err != nil {
+		return nil, err
+	}
+	psh, err := m.sub(2)
+	if err != nil {
+		return nil, err
+	}
+	hash, total, pshHash := m.bytes(1), psh.uint(1), psh.bytes(2)
+	if len(hash) == 0 && total == 0 && len(pshHash) == 0 {
+		return nil, nil
+	}
+	canonicalPSH := appendBytes(appendVarint(nil, 1, total), 2, pshHash)
+	return appendEmbedded(appendBytes(nil, 1, hash), 2, canonicalPSH), nil
+}
+
+// canonicalTimestamp re-encodes a google.protobuf.Timestamp
+func canonicalTimestamp(raw []byte) ([]byte, error) {
+	m, err := parseMessage(raw)
+	if err != nil {
+		return nil, err
+	}
+	return appendVarint(appendVarint(nil, 1, m.uint(1)), 2, uint64(int64(int32(m.uint(2))))), nil
+}
+
+// signedTimestamp returns the raw timestamp inside sign bytes of s's kind
+func (s *signable) signedTimestamp(signBytes []byte) ([]byte, error) {
+	body, err := readDelimited(bytes.NewReader(signBytes), len(signBytes))
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseMessage(body)
+	if err != nil {
+		return nil, err
+	}
+	num := 5
+	if s.proposal {
+		num = 6
+	}
+	f, ok := m.get(num)
+	if !ok || f.wire != wireBytes {
+		return nil, errors.New("no timestamp in last sign bytes")
+	}
+	return f.b, nil
+}
@@ -0,0 +1,80 @@
+package privval
+
+import (
+	"bytes"
+	"testing"
+)
+
+// zeroSeconds is Go's zero time in Unix seconds
+var zeroSeconds int64 = -62135596800
+
+// zeroTime is the encoded google.protobuf.Timestamp of Go's zero time
+var zeroTime = appendVarint(nil, 1, uint64(zeroSeconds))
+
+// Vectors of CometBFT's TestVoteSignBytesTestVectors
+func TestVoteSignBytes(t *testing.T) {
+	one := []byte{1, 0, 0, 0, 0, 0, 0, 0}
+	ts := []byte{0x2a, 0xb, 0x8, 0x80, 0x92, 0xb8, 0xc3, 0x98, 0xfe, 0xff, 0xff, 0xff, 0x1}
+	cat := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+	tests := []struct {
+		name    string
+		chainID string
+		vote    *signable
+		want    []byte
+	}{
+		{"empty", "", &signable{}, cat([]byte{0xd}, ts)},
+		{"precommit", "", &signable{msgType: typePrecommit, height: 1, round: 1},
+			cat([]byte{0x21, 0x8, 0x2, 0x11}, one, []byte{0x19}, one, ts)},
+		{"prevote", "", &signable{msgType: typePrevote, height: 1, round: 1},
+			cat([]byte{0x21, 0x8, 0x1, 0x11}, one, []byte{0x19}, one, ts)},
+		{"no type", "", &signable{height: 1, round: 1},
+			cat([]byte{0x1f, 0x11}, one, []byte{0x19}, one, ts)},
+		{"chain ID", "test_chain_id", &signable{height: 1, round: 1},
+			cat([]byte{0x2e, 0x11}, one, []byte{0x19}, one, ts, []byte{0x32, 0xd}, []byte("test_chain_id"))},
+	}
+	for _, tt := range tests {
+		got, err := tt.vote.signBytes(tt.chainID, zeroTime)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: sign bytes %x, want %x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProposalSignBytes(t *testing.T) {
+	hash := bytes.Repeat([]byte{0xab}, 32)
+	blockID := appendEmbedded(appendBytes(nil, 1, hash), 2, appendBytes(appendVarint(nil, 1, 1), 2, hash))
+	raw := appendVarint(nil, 1, typeProposal)
+	raw = appendVarint(raw, 2, 1)
+	raw = appendVarint(raw, 3, 1)
+	raw = appendVarint(raw, 4, uint64(1<<64-1)) // pol_round -1
+	raw = appendEmbedded(raw, 5, blockID)
+	raw = appendEmbedded(raw, 6, zeroTime)
+	p, err := parseProposal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// CanonicalProposal: type, sfixed64 height and round, pol_round,
+	// block_id, timestamp, chain_id
+	want := []byte{0x8, 0x20, 0x11, 1, 0, 0, 0, 0, 0, 0, 0, 0x19, 1, 0, 0, 0, 0, 0, 0, 0}
+	want = append(want, 0x20, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x1)
+	want = appendEmbedded(want, 5, blockID)
+	want = append(want, 0x32, 0xb, 0x8, 0x80, 0x92, 0xb8, 0xc3, 0x98, 0xfe, 0xff, 0xff, 0xff, 0x1)
+	want = appendBytes(want, 7, []byte("test_chain_id"))
+	want = delimited(want)
+
+	got, err := p.signBytes("test_chain_id", p.timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("sign bytes %x, want %x", got, want)
+	}
+	if p.step() != stepPropose {
+		t.Errorf("step %d, want %d", p.step(), stepPropose)
+	}
+}
@@ -0,0 +1,16 @@
+package privval
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// Test vector of the merlin crate
+func TestTranscriptChallenge(t *testing.T) {
+	tr := newTranscript("test protocol")
+	tr.appendMessage("some label", []byte("some data"))
+	got := hex.EncodeToString(tr.extractBytes("challenge", 32))
+	if want := "d5a21972d0d5fe320c0d263fac7fffb8145aa640af6e9bca177c03c7efcf0615"; got != want {
+		t.Errorf("challenge %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+package privval
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// field is one decoded protobuf field. Unknown fields are kept so messages
+// can be echoed back with only the signature changed.
+type field struct {
+	num  int
+	wire int
+	v    uint64
+	b    []byte
+}
+
+// message is a protobuf message as a list of fields in wire order
+type message []field
+
+func parseMessage(data []byte) (message, error) {
+	var m message
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		data = data[n:]
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			if f.v, n = binary.Uvarint(data); n <= 0 {
+				return nil, errTruncated
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return nil, errTruncated
+			}
+			f.v = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return nil, errTruncated
+			}
+			f.v = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return nil, errTruncated
+			}
+			f.b = data[n : n+int(l)]
+			data = data[n+int(l):]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", f.wire)
+		}
+		m = append(m, f)
+	}
+	return m, nil
+}
+
+// get returns the last field num, as protobuf merges repeated scalars
+func (m message) get(num int) (field, bool) {
+	for i := len(m) - 1; i >= 0; i-- {
+		if m[i].num == num {
+			return m[i], true
+		}
+	}
+	return field{}, false
+}
+
+func (m message) uint(num int) uint64 {
+	f, _ := m.get(num)
+	return f.v
+}
+
+// int returns a varint field as a signed int64 (int32 and int64 both sign
+// extend to 64 bits on the wire)
+func (m message) int(num int) int64 {
+	return int64(m.uint(num))
+}
+
+func (m message) bytes(num int) []byte {
+	f, _ := m.get(num)
+	return f.b
+}
+
+func (m message) sub(num int) (message, error) {
+	return parseMessage(m.bytes(num))
+}
+
+// set replaces field num with a bytes field, or appends it
+func (m message) set(num int, b []byte) message {
+	out := make(message, 0, len(m)+1)
+	done := false
+	for _, f := range m {
+		if f.num != num {
+			out = append(out, f)
+		} else if !done {
+			out = append(out, field{num: num, wire: wireBytes, b: b})
+			done = true
+		}
+	}
+	if !done {
+		out = append(out, field{num: num, wire: wireBytes, b: b})
+	}
+	return out
+}
+
+func (m message) marshal() []byte {
+	var b []byte
+	for _, f := range m {
+		b = appendKey(b, f.num, f.wire)
+		switch f.wire {
+		case wireVarint:
+			b = binary.AppendUvarint(b, f.v)
+		case wireFixed64:
+			b = binary.LittleEndian.AppendUint64(b, f.v)
+		case wireFixed32:
+			b = binary.LittleEndian.AppendUint32(b, uint32(f.v))
+		case wireBytes:
+			b = binary.AppendUvarint(b, uint64(len(f.b)))
+			b = append(b, f.b...)
+		}
+	}
+	return b
+}
+
+func appendKey(b []byte, num, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(wire))
+}
+
+// appendVarint appends a varint field, omitted when zero as proto3 does
+func appendVarint(b []byte, num int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendKey(b, num, wireVarint), v)
+}
+
+// appendSfixed64 appends an sfixed64 field, omitted when zero
+func appendSfixed64(b []byte, num int, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.LittleEndian.AppendUint64(appendKey(b, num, wireFixed64), uint64(v))
+}
+
+// appendBytes appends a bytes or string field, omitted when empty
+func appendBytes(b []byte, num int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	return appendEmbedded(b, num, v)
+}
+
+// appendEmbedded appends a non-nullable embedded message, written even
+// when empty
+func appendEmbedded(b []byte, num int, v []byte) []byte {
+	b = binary.AppendUvarint(appendKey(b, num, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// delimited prefixes data with its uvarint length
+func delimited(data []byte) []byte {
+	return append(binary.AppendUvarint(nil, uint64(len(data))), data...)
+}
@@ -0,0 +1,232 @@
+package privval
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// SecretConnection framing, as in CometBFT's p2p/conn
+const (
+	dataLenSize     = 4
+	dataMaxSize     = 1024
+	totalFrameSize  = dataMaxSize + dataLenSize
+	sealedFrameSize = totalFrameSize + aeadTagSize
+	maxHandshakeMsg = 1024
+	aeadTagSize     = 16
+)
+
+const (
+	transcriptLabel = "TENDERMINT_SECRET_CONNECTION_TRANSCRIPT_HASH"
+	keyAndChallenge = "TENDERMINT_SECRET_CONNECTION_KEY_AND_CHALLENGE_GEN"
+)
+
+// secretConn is an authenticated, encrypted CometBFT SecretConnection
+type secretConn struct {
+	conn      net.Conn
+	remotePub ed25519.PublicKey
+
+	recvAead, sendAead   cipher.AEAD
+	recvNonce, sendNonce [chacha20poly1305.NonceSize]byte
+	recvBuf              []byte
+}
+
+// newSecretConn runs the SecretConnection handshake on conn, authenticating
+// this end with identity
+func newSecretConn(conn net.Conn, identity ed25519.PrivateKey) (*secretConn, error) {
+	var ephPriv [32]byte
+	if _, err := rand.Read(ephPriv[:]); err != nil {
+		return nil, err
+	}
+	defer zero(ephPriv[:])
+	ephPub, err := curve25519.X25519(ephPriv[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exchange ephemeral keys as length-delimited BytesValue messages
+	if _, err := conn.Write(delimited(appendBytes(nil, 1, ephPub))); err != nil {
+		return nil, err
+	}
+	msg, err := readDelimited(conn, maxHandshakeMsg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ephemeral key: %w", err)
+	}
+	m, err := parseMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	remEphPub := m.bytes(1)
+	if len(remEphPub) != 32 {
+		return nil, errors.New("invalid ephemeral key")
+	}
+	if bytes.Equal(ephPub, remEphPub) {
+		return nil, errors.New("remote ephemeral key equals ours")
+	}
+
+	lo, hi := ephPub, remEphPub
+	if bytes.Compare(lo, hi) > 0 {
+		lo, hi = hi, lo
+	}
+	locIsLeast := bytes.Equal(lo, ephPub)
+
+	dh, err := curve25519.X25519(ephPriv[:], remEphPub)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote ephemeral key: %w", err)
+	}
+	defer zero(dh)
+
+	t := newTranscript(transcriptLabel)
+	t.appendMessage("EPHEMERAL_LOWER_PUBLIC_KEY", lo)
+	t.appendMessage("EPHEMERAL_UPPER_PUBLIC_KEY", hi)
+	t.appendMessage("DH_SECRET", dh)
+
+	secrets := make([]byte, 2*chacha20poly1305.KeySize+32)
+	defer zero(secrets)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, dh, nil, []byte(keyAndChallenge)), secrets); err != nil {
+		return nil, err
+	}
+	recvKey, sendKey := secrets[:32], secrets[32:64]
+	if !locIsLeast {
+		recvKey, sendKey = sendKey, recvKey
+	}
+	sc := &secretConn{conn: conn}
+	if sc.recvAead, err = chacha20poly1305.New(recvKey); err != nil {
+		return nil, err
+	}
+	if sc.sendAead, err = chacha20poly1305.New(sendKey); err != nil {
+		return nil, err
+	}
+
+	// Both ends sign the challenge and exchange AuthSigMessages
+	challenge := t.extractBytes("SECRET_CONNECTION_MAC", 32)
+	pub := identity.Public().(ed25519.PublicKey)
+	auth := appendEmbedded(nil, 1, appendBytes(nil, 1, pub))
+	auth = appendBytes(auth, 2, ed25519.Sign(identity, challenge))
+	if _, err := sc.Write(delimited(auth)); err != nil {
+		return nil, err
+	}
+	msg, err = readDelimited(sc, maxHandshakeMsg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth signature: %w", err)
+	}
+	m, err = parseMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := m.sub(1)
+	if err != nil {
+		return nil, err
+	}
+	remPub := pk.bytes(1)
+	if len(remPub) != ed25519.PublicKeySize {
+		return nil, errors.New("remote identity is not an ed25519 key")
+	}
+	if !ed25519.Verify(remPub, challenge, m.bytes(2)) {
+		return nil, errors.New("remote auth signature is invalid")
+	}
+	sc.remotePub = remPub
+	return sc, nil
+}
+
+// Write encrypts p in frames of up to dataMaxSize bytes
+func (sc *secretConn) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > dataMaxSize {
+			chunk = chunk[:dataMaxSize]
+		}
+		var frame [totalFrameSize]byte
+		binary.LittleEndian.PutUint32(frame[:], uint32(len(chunk)))
+		copy(frame[dataLenSize:], chunk)
+		sealed := sc.sendAead.Seal(nil, sc.sendNonce[:], frame[:], nil)
+		incrNonce(&sc.sendNonce)
+		if _, err := sc.conn.Write(sealed); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+// Read decrypts the next frame unless data of the last one is left
+func (sc *secretConn) Read(p []byte) (int, error) {
+	if len(sc.recvBuf) == 0 {
+		sealed := make([]byte, sealedFrameSize)
+		if _, err := io.ReadFull(sc.conn, sealed); err != nil {
+			return 0, err
+		}
+		frame, err := sc.recvAead.Open(nil, sc.recvNonce[:], sealed, nil)
+		if err != nil {
+			return 0, errors.New("failed to decrypt SecretConnection frame")
+		}
+		incrNonce(&sc.recvNonce)
+		l := binary.LittleEndian.Uint32(frame)
+		if l > dataMaxSize {
+			return 0, errors.New("SecretConnection frame length exceeds maximum")
+		}
+		sc.recvBuf = frame[dataLenSize : dataLenSize+l]
+	}
+	n := copy(p, sc.recvBuf)
+	sc.recvBuf = sc.recvBuf[n:]
+	return n, nil
+}
+
+func (sc *secretConn) Close() error {
+	return sc.conn.Close()
+}
+
+// incrNonce increments the little-endian counter in the last 8 bytes
+func incrNonce(nonce *[chacha20poly1305.NonceSize]byte) {
+	counter := binary.LittleEndian.Uint64(nonce[4:])
+	binary.LittleEndian.PutUint64(nonce[4:], counter+1)
+}
+
+// readDelimited reads a uvarint length-prefixed message of at most max
+// bytes, without reading past its end
+func readDelimited(r io.Reader, max int) ([]byte, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = byteReader{r}
+	}
+	l, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(max) {
+		return nil, fmt.Errorf("message of %d bytes exceeds %d", l, max)
+	}
+	buf := make([]byte, l)
+	_, err = io.ReadFull(r, buf)
+	return buf, err
+}
+
+// byteReader reads one byte at a time from an unbuffered reader
+type byteReader struct {
+	io.Reader
+}
+
+func (r byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r, b[:])
+	return b[0], err
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
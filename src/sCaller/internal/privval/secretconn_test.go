@@ -0,0 +1,163 @@
+package privval
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// secretConnPair returns both ends of a SecretConnection over loopback TCP
+// and their identities. net.Pipe cannot be used: both ends write their
+// ephemeral key before reading.
+func secretConnPair(t *testing.T) (a, b *secretConn, idA, idB ed25519.PrivateKey) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, idA, _ = ed25519.GenerateKey(rand.Reader)
+	_, idB, _ = ed25519.GenerateKey(rand.Reader)
+
+	type result struct {
+		sc  *secretConn
+		err error
+	}
+	accepted := make(chan result, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			accepted <- result{err: err}
+			return
+		}
+		c.SetDeadline(time.Now().Add(dialTimeout))
+		sc, err := newSecretConn(c, idB)
+		c.SetDeadline(time.Time{})
+		accepted <- result{sc, err}
+	}()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetDeadline(time.Now().Add(dialTimeout))
+	a, err = newSecretConn(c, idA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetDeadline(time.Time{})
+	r := <-accepted
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	t.Cleanup(func() {
+		a.Close()
+		r.sc.Close()
+	})
+	return a, r.sc, idA, idB
+}
+
+func TestSecretConnHandshake(t *testing.T) {
+	a, b, idA, idB := secretConnPair(t)
+	if !bytes.Equal(a.remotePub, idB.Public().(ed25519.PublicKey)) {
+		t.Error("dialer did not authenticate the listener's identity")
+	}
+	if !bytes.Equal(b.remotePub, idA.Public().(ed25519.PublicKey)) {
+		t.Error("listener did not authenticate the dialer's identity")
+	}
+
+	// Messages longer than a frame are split and reassembled, both ways
+	for _, dir := range []struct{ from, to *secretConn }{{a, b}, {b, a}} {
+		msg := make([]byte, 3*dataMaxSize+100)
+		rand.Read(msg)
+		errc := make(chan error, 1)
+		go func() {
+			_, err := dir.from.Write(msg)
+			errc <- err
+		}()
+		got := make([]byte, len(msg))
+		if _, err := io.ReadFull(dir.to, got); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Error("received data differs from sent data")
+		}
+	}
+}
+
+func TestSecretConnRejectsTamperedFrame(t *testing.T) {
+	a, b, _, _ := secretConnPair(t)
+	// Seal a frame with the sender's key, flip a bit and pass it on raw
+	frame := make([]byte, totalFrameSize)
+	frame[3] = 1
+	sealed := a.sendAead.Seal(nil, a.sendNonce[:], frame, nil)
+	sealed[10] ^= 1
+	go a.conn.Write(sealed)
+	if _, err := b.Read(make([]byte, 1)); err == nil {
+		t.Error("tampered frame was accepted")
+	}
+}
+
+func TestSignerOverSecretConn(t *testing.T) {
+	s := newTestSigner(t)
+	sekaid, signer, _, _ := secretConnPair(t)
+	go s.Serve(signer)
+
+	r := bufio.NewReader(sekaid)
+	call := func(req []byte) message {
+		t.Helper()
+		if _, err := sekaid.Write(delimited(req)); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := readDelimited(r, maxMsgSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := parseMessage(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	pk, err := call(appendEmbedded(nil, msgPubKeyRequest, appendBytes(nil, 1, []byte(testChainID)))).sub(msgPubKeyResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := pk.sub(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := ed25519.PublicKey(key.bytes(1))
+	if !bytes.Equal(pub, s.key.PubKey()) {
+		t.Fatalf("public key %x, want %x", pub, s.key.PubKey())
+	}
+
+	req := appendBytes(appendEmbedded(nil, 1, testVote(typePrevote, 7, 0, 0xbb, 100)), 2, []byte(testChainID))
+	resp, err := call(appendEmbedded(nil, msgSignVoteRequest, req)).sub(msgSignedVoteResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err := resp.sub(2); err == nil && len(e) > 0 {
+		t.Fatalf("vote refused: %s", e.bytes(2))
+	}
+	v, err := parseVote(resp.bytes(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signBytes, err := v.signBytes(testChainID, v.timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(pub, signBytes, v.msg.bytes(v.signatureField())) {
+		t.Error("vote signature does not verify with the signer's public key")
+	}
+}
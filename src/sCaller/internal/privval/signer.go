@@ -0,0 +1,244 @@
+package privval
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"scaller/internal/keys"
+	"scaller/internal/node"
+)
+
+// privval.Message fields, one per request and response type
+const (
+	msgPubKeyRequest          = 1
+	msgPubKeyResponse         = 2
+	msgSignVoteRequest        = 3
+	msgSignedVoteResponse     = 4
+	msgSignProposalRequest    = 5
+	msgSignedProposalResponse = 6
+	msgPingRequest            = 7
+	msgPingResponse           = 8
+)
+
+// maxMsgSize bounds privval messages, as CometBFT's remote signer does
+const maxMsgSize = 10 * 1024
+
+// dialTimeout bounds connecting to sekaid and the SecretConnection handshake
+const dialTimeout = 5 * time.Second
+
+// Signer answers sekaid's privval requests over its priv_validator_laddr,
+// signing with a file-backed ed25519 key and refusing to sign before or
+// in conflict with the last signed height, round and step
+type Signer struct {
+	chainID   string
+	key       *keys.ConsensusKey
+	statePath string
+	identity  ed25519.PrivateKey
+
+	// Logf, if set, reports each signature and refusal
+	Logf func(format string, args ...interface{})
+
+	// VoteExtensions is set for CometBFT 0.38 nodes, where a precommit
+	// request without skip_extension_signing asks for a vote extension
+	// signature, which is not supported
+	VoteExtensions bool
+
+	mu    sync.Mutex
+	state *State
+}
+
+// NewSigner returns a signer for chainID with the state at statePath. The
+// connection identity is a new random key, as sekaid does not pin it.
+func NewSigner(chainID string, key *keys.ConsensusKey, statePath string) (*Signer, error) {
+	state, err := LoadState(statePath)
+	if err != nil {
+		return nil, err
+	}
+	_, identity, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{chainID: chainID, key: key, statePath: statePath, identity: identity, state: state}, nil
+}
+
+// State returns the last signed height, round and step
+func (s *Signer) State() node.SignState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.SignState
+}
+
+// DialAndServe connects to sekaid's laddr and answers requests until the
+// connection fails. tcp:// connections use SecretConnection; unix://
+// sockets are plain, as in CometBFT.
+func (s *Signer) DialAndServe(laddr string) error {
+	u, err := url.Parse(laddr)
+	if err != nil {
+		return err
+	}
+	var conn io.ReadWriteCloser
+	switch u.Scheme {
+	case "tcp":
+		c, err := net.DialTimeout("tcp", u.Host, dialTimeout)
+		if err != nil {
+			return err
+		}
+		c.SetDeadline(time.Now().Add(dialTimeout))
+		sc, err := newSecretConn(c, s.identity)
+		if err != nil {
+			c.Close()
+			return fmt.Errorf("SecretConnection handshake: %w", err)
+		}
+		c.SetDeadline(time.Time{})
+		conn = sc
+	case "unix":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		if conn, err = net.DialTimeout("unix", path, dialTimeout); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported laddr scheme %q", u.Scheme)
+	}
+	defer conn.Close()
+	return s.Serve(conn)
+}
+
+// Serve answers length-delimited privval requests on conn
+func (s *Signer) Serve(conn io.ReadWriter) error {
+	r := bufio.NewReader(conn)
+	for {
+		req, err := readDelimited(r, maxMsgSize)
+		if err != nil {
+			return err
+		}
+		resp, err := s.handle(req)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(delimited(resp)); err != nil {
+			return err
+		}
+	}
+}
+
+// handle returns the response to one privval.Message request
+func (s *Signer) handle(req []byte) ([]byte, error) {
+	m, err := parseMessage(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(m) != 1 || m[0].wire != wireBytes {
+		return nil, errors.New("malformed privval message")
+	}
+	body, err := parseMessage(m[0].b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch m[0].num {
+	case msgPingRequest:
+		return appendEmbedded(nil, msgPingResponse, nil), nil
+
+	case msgPubKeyRequest:
+		var resp []byte
+		if chainID := string(body.bytes(1)); chainID != s.chainID {
+			resp = remoteError(nil, fmt.Errorf("chain ID %q does not match %q", chainID, s.chainID))
+		} else {
+			resp = appendEmbedded(nil, 1, appendBytes(nil, 1, s.key.PubKey()))
+		}
+		return appendEmbedded(nil, msgPubKeyResponse, resp), nil
+
+	case msgSignVoteRequest, msgSignProposalRequest:
+		parse, respNum := parseVote, msgSignedVoteResponse
+		if m[0].num == msgSignProposalRequest {
+			parse, respNum = parseProposal, msgSignedProposalResponse
+		}
+		raw := body.bytes(1)
+		v, err := parse(raw)
+		if err == nil && s.VoteExtensions && v.msgType == typePrecommit && body.uint(3) == 0 {
+			err = errors.New("vote extension signing is not supported")
+			s.logf("Refused to sign %s: %v", v, err)
+		}
+		if err != nil {
+			resp := remoteError(appendEmbedded(nil, 1, raw), err)
+			return appendEmbedded(nil, respNum, resp), nil
+		}
+		signed, err := s.sign(v, string(body.bytes(2)))
+		if err != nil {
+			s.logf("Refused to sign %s: %v", v, err)
+			resp := remoteError(appendEmbedded(nil, 1, raw), err)
+			return appendEmbedded(nil, respNum, resp), nil
+		}
+		return appendEmbedded(nil, respNum, appendEmbedded(nil, 1, signed)), nil
+	}
+	return nil, fmt.Errorf("unexpected privval message %d", m[0].num)
+}
+
+// sign signs v, or repeats the last signature for the same step when only
+// the timestamp differs, and returns v with the signature set
+func (s *Signer) sign(v *signable, chainID string) ([]byte, error) {
+	if chainID != s.chainID {
+		return nil, fmt.Errorf("chain ID %q does not match %q", chainID, s.chainID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hrs := node.SignState{Height: v.height, Round: v.round, Step: v.step()}
+	same, err := s.state.check(hrs)
+	if err != nil {
+		return nil, err
+	}
+	signBytes, err := v.signBytes(chainID, v.timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, sig := v.timestamp, []byte(nil)
+	if same {
+		if !bytes.Equal(signBytes, s.state.SignBytes) {
+			// CometBFT may ask again with a new timestamp; answer with the old one
+			last, err := v.signedTimestamp(s.state.SignBytes)
+			if err != nil {
+				return nil, err
+			}
+			again, err := v.signBytes(chainID, last)
+			if err != nil || !bytes.Equal(again, s.state.SignBytes) {
+				return nil, errors.New("conflicting data for the last signed step")
+			}
+			timestamp = last
+		}
+		sig = s.state.Signature
+	} else {
+		sig = ed25519.Sign(s.key.PrivKey(), signBytes)
+		next := &State{SignState: hrs, Signature: sig, SignBytes: signBytes}
+		if err := next.Save(s.statePath); err != nil {
+			return nil, fmt.Errorf("failed to save signing state: %w", err)
+		}
+		s.state = next
+		s.logf("Signed %s", v)
+	}
+	return v.msg.set(v.timestampField(), timestamp).set(v.signatureField(), sig).marshal(), nil
+}
+
+func (s *Signer) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// remoteError appends a RemoteSignerError for err to a response
+func remoteError(resp []byte, err error) []byte {
+	return appendEmbedded(resp, 2, appendBytes(appendVarint(nil, 1, 1), 2, []byte(err.Error())))
+}
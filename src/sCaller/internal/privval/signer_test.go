@@ -0,0 +1,51 @@
+package privval
+
+import (
+	"strings"
+	"testing"
+)
+
+// voteResponseError returns the RemoteSignerError description in a response
+// to a SignVoteRequest
+func voteResponseError(t *testing.T, resp []byte) string {
+	t.Helper()
+	m, err := parseMessage(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := m.sub(msgSignedVoteResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := body.sub(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(e.bytes(2))
+}
+
+func TestSignerVoteExtensions(t *testing.T) {
+	s := newTestSigner(t)
+	s.VoteExtensions = true
+	request := func(vote []byte, skipExtension bool) []byte {
+		req := appendBytes(appendEmbedded(nil, 1, vote), 2, []byte(testChainID))
+		if skipExtension {
+			req = appendVarint(req, 3, 1)
+		}
+		resp, err := s.handle(appendEmbedded(nil, msgSignVoteRequest, req))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if e := voteResponseError(t, request(testVote(typePrevote, 5, 0, 0xaa, 100), false)); e != "" {
+		t.Errorf("prevote refused: %s", e)
+	}
+	if e := voteResponseError(t, request(testVote(typePrecommit, 5, 0, 0xaa, 100), false)); !strings.Contains(e, "vote extension") {
+		t.Errorf("precommit asking for an extension signature: error %q", e)
+	}
+	if e := voteResponseError(t, request(testVote(typePrecommit, 5, 0, 0xaa, 100), true)); e != "" {
+		t.Errorf("precommit skipping extension signing refused: %s", e)
+	}
+}
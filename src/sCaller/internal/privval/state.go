@@ -0,0 +1,97 @@
+package privval
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"scaller/internal/config"
+	"scaller/internal/node"
+)
+
+// State is the last height, round and step signed at, with the sign bytes
+// and signature to answer a repeated request. It is stored in the format of
+// priv_validator_state.json.
+type State struct {
+	node.SignState
+	Signature []byte
+	SignBytes []byte
+}
+
+type stateFile struct {
+	Height    string `json:"height"`
+	Round     int64  `json:"round"`
+	Step      int64  `json:"step"`
+	Signature []byte `json:"signature,omitempty"`
+	SignBytes string `json:"signbytes,omitempty"`
+}
+
+// LoadState reads the signing state at path. A missing file is the zero
+// state; a TMKMS state file is read for its height, round and step.
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	hrs, err := node.ParseSignState(data)
+	if err != nil {
+		return nil, err
+	}
+	var f struct {
+		Signature []byte `json:"signature"`
+		SignBytes string `json:"signbytes"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	sb, err := hex.DecodeString(f.SignBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signbytes in %s: %w", path, err)
+	}
+	return &State{SignState: hrs, Signature: f.Signature, SignBytes: sb}, nil
+}
+
+// Save writes s to path, synced to disk before the signature is released
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(stateFile{
+		Height:    fmt.Sprint(s.Height),
+		Round:     s.Round,
+		Step:      s.Step,
+		Signature: s.Signature,
+		SignBytes: fmt.Sprintf("%X", s.SignBytes),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return config.WriteFileAtomic(path, data, 0600)
+}
+
+// check compares height, round and step with the last signed ones. It
+// reports a repeat of the last step, and refuses anything before it.
+func (s *State) check(hrs node.SignState) (same bool, err error) {
+	switch {
+	case hrs.Less(s.SignState):
+		return false, fmt.Errorf("%s regression: last signed %s, asked for %s", regression(s.SignState, hrs), s.SignState, hrs)
+	case hrs == s.SignState && hrs != (node.SignState{}):
+		if len(s.SignBytes) == 0 {
+			return false, errors.New("no sign bytes recorded for the last signed step")
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func regression(last, hrs node.SignState) string {
+	switch {
+	case hrs.Height < last.Height:
+		return "height"
+	case hrs.Round < last.Round:
+		return "round"
+	}
+	return "step"
+}
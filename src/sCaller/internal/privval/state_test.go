@@ -0,0 +1,108 @@
+package privval
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"scaller/internal/keys"
+	"scaller/internal/node"
+)
+
+const testChainID = "test_chain_id"
+
+func newTestSigner(t *testing.T) *Signer {
+	t.Helper()
+	ck, err := keys.GenerateConsensusKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSigner(testChainID, ck, filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// testVote returns a raw Vote for a block with hash filled with b
+func testVote(msgType uint64, height, round int64, b byte, seconds uint64) []byte {
+	hash := bytes.Repeat([]byte{b}, 32)
+	blockID := appendEmbedded(appendBytes(nil, 1, hash), 2, appendBytes(appendVarint(nil, 1, 1), 2, hash))
+	v := appendVarint(nil, 1, msgType)
+	v = appendVarint(v, 2, uint64(height))
+	v = appendVarint(v, 3, uint64(round))
+	v = appendEmbedded(v, 4, blockID)
+	return appendEmbedded(v, 5, appendVarint(nil, 1, seconds))
+}
+
+func signVote(t *testing.T, s *Signer, raw []byte) ([]byte, error) {
+	t.Helper()
+	v, err := parseVote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.sign(v, testChainID)
+}
+
+func TestStateRefusesRegression(t *testing.T) {
+	s := &State{SignState: node.SignState{Height: 10, Round: 2, Step: stepPrevote}, SignBytes: []byte{1}}
+	for _, hrs := range []node.SignState{
+		{Height: 9, Round: 5, Step: stepPrecommit},
+		{Height: 10, Round: 1, Step: stepPrecommit},
+		{Height: 10, Round: 2, Step: stepPropose},
+	} {
+		if _, err := s.check(hrs); err == nil {
+			t.Errorf("check(%s) after %s succeeded", hrs, s.SignState)
+		}
+	}
+	for _, hrs := range []node.SignState{
+		{Height: 10, Round: 2, Step: stepPrecommit},
+		{Height: 10, Round: 3, Step: stepPropose},
+		{Height: 11},
+	} {
+		if same, err := s.check(hrs); err != nil || same {
+			t.Errorf("check(%s) after %s = %v, %v", hrs, s.SignState, same, err)
+		}
+	}
+}
+
+func TestSignerRefusesConflictingBlock(t *testing.T) {
+	s := newTestSigner(t)
+	first, err := signVote(t, s, testVote(typePrevote, 5, 0, 0xaa, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signVote(t, s, testVote(typePrevote, 5, 0, 0xbb, 100)); err == nil {
+		t.Error("signed a second block at the same height, round and step")
+	}
+	if _, err := signVote(t, s, testVote(typePrevote, 4, 0, 0xaa, 100)); err == nil {
+		t.Error("signed below the last height")
+	}
+
+	// A repeat with only a new timestamp gets the first signature back
+	again, err := signVote(t, s, testVote(typePrevote, 5, 0, 0xaa, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, first) {
+		t.Error("repeated request did not return the first signed vote")
+	}
+}
+
+func TestLoadStateWithoutSignBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "priv_validator_state.json")
+	data := `{"height": "5", "round": 0, "step": 2, "signbytes": ""}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	st, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.check(node.SignState{Height: 5, Step: stepPrevote})
+	if err == nil || !strings.Contains(err.Error(), "no sign bytes") {
+		t.Errorf("check of the last step without sign bytes = %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package profile
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"scaller/internal/config"
+
+	"github.com/BurntSushi/toml"
+)
+
+//go:embed profiles/*.toml
+var builtin embed.FS
+
+// Profile bundles everything needed to join a network
+type Profile struct {
+	Name              string   `toml:"name"`
+	ChainID           string   `toml:"chain_id"`
+	GenesisSHA256     string   `toml:"genesis_sha256"`
+	RPCNodes          []string `toml:"rpc_nodes"`
+	Seeds             []string `toml:"seeds"`
+	PersistentPeers   []string `toml:"persistent_peers"`
+	Pruning           string   `toml:"pruning"`
+	Preset            string   `toml:"preset"`
+	RemoteSigner      bool     `toml:"remote_signer"`
+	RemoteSignerLaddr string   `toml:"remote_signer_laddr"`
+
+	StateSync struct {
+		Enable           bool  `toml:"enable"`
+		SnapshotInterval int64 `toml:"snapshot_interval"`
+	} `toml:"statesync"`
+
+	// Scall holds scall.toml-style overrides ("config.p2p.seeds" = "...")
+	Scall config.ScallConfig `toml:"scall"`
+}
+
+// Load reads a profile from a file path, or a built-in profile by name
+func Load(nameOrPath string) (*Profile, error) {
+	if _, err := os.Stat(nameOrPath); err == nil {
+		data, err := os.ReadFile(nameOrPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile: %w", err)
+		}
+		return parse(nameOrPath, data)
+	}
+
+	data, err := builtin.ReadFile(path.Join("profiles", nameOrPath+".toml"))
+	if err != nil {
+		return nil, fmt.Errorf("profile %q is neither a file nor a built-in profile (available: %s)",
+			nameOrPath, strings.Join(Builtin(), ", "))
+	}
+	return parse(nameOrPath, data)
+}
+
+// Builtin returns the names of profiles embedded in the binary
+func Builtin() []string {
+	entries, _ := builtin.ReadDir("profiles")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".toml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parse(source string, data []byte) (*Profile, error) {
+	var p Profile
+	if _, err := toml.Decode(string(data), &p); err != nil {
+		return nil, fmt.Errorf("failed to parse profile %s: %w", source, err)
+	}
+	if p.Name == "" {
+		p.Name = source
+	}
+	if err := p.Scall.RegisterFiles(); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", source, err)
+	}
+	for key := range p.Scall {
+		if _, _, err := config.SplitKey("", key); err != nil {
+			return nil, fmt.Errorf("profile %s: scall %w", source, err)
+		}
+	}
+	return &p, nil
+}
+
+// ResponsiveRPCNodes returns the RPC nodes that answer /status and, if
+// chainID is set, report that network. Order is preserved.
+func ResponsiveRPCNodes(rpcNodes []string, chainID string) []string {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	var result []string
+	for _, node := range rpcNodes {
+		url := node
+		if !strings.HasPrefix(url, "http") {
+			url = "http://" + url
+		}
+		resp, err := client.Get(strings.TrimRight(url, "/") + "/status")
+		if err != nil {
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			continue
+		}
+
+		var status struct {
+			Result struct {
+				NodeInfo struct {
+					Network string `json:"network"`
+				} `json:"node_info"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(body, &status); err != nil {
+			continue
+		}
+		if chainID != "" && status.Result.NodeInfo.Network != chainID {
+			continue
+		}
+		result = append(result, node)
+	}
+	return result
+}
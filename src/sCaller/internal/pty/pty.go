@@ -0,0 +1,79 @@
+package pty
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Open allocates a pseudo terminal and returns its master and slave ends.
+// The slave is in canonical mode with echo disabled: every read from it
+// returns at most one line and input written to the master is never echoed.
+func Open() (master, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			master.Close()
+		}
+	}()
+
+	var unlock int32
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		return nil, nil, fmt.Errorf("failed to unlock pty: %w", err)
+	}
+	var n uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		return nil, nil, fmt.Errorf("failed to get pty number: %w", err)
+	}
+
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var t syscall.Termios
+	if err := ioctl(slave.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&t))); err != nil {
+		slave.Close()
+		return nil, nil, fmt.Errorf("failed to read pty attributes: %w", err)
+	}
+	t.Lflag |= syscall.ICANON
+	t.Lflag &^= syscall.ECHO | syscall.ECHONL
+	if err := ioctl(slave.Fd(), syscall.TCSETS, uintptr(unsafe.Pointer(&t))); err != nil {
+		slave.Close()
+		return nil, nil, fmt.Errorf("failed to disable pty echo: %w", err)
+	}
+	return master, slave, nil
+}
+
+func ioctl(fd, req, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// IsTerminal reports whether f is a terminal
+func IsTerminal(f *os.File) bool {
+	var t syscall.Termios
+	return ioctl(f.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&t))) == nil
+}
+
+// NoEcho turns off echo on the terminal f until restore is called, so a
+// secret typed at a prompt is not shown
+func NoEcho(f *os.File) (restore func(), err error) {
+	var t syscall.Termios
+	if err := ioctl(f.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&t))); err != nil {
+		return nil, err
+	}
+	saved := t
+	t.Lflag &^= syscall.ECHO
+	t.Lflag |= syscall.ICANON | syscall.ECHONL
+	if err := ioctl(f.Fd(), syscall.TCSETS, uintptr(unsafe.Pointer(&t))); err != nil {
+		return nil, err
+	}
+	return func() { ioctl(f.Fd(), syscall.TCSETS, uintptr(unsafe.Pointer(&saved))) }, nil
+}
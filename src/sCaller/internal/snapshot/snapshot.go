@@ -0,0 +1,211 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LatestManifest is the manifest name that always points at the newest snapshot
+const LatestManifest = "latest.json"
+
+// excludedFiles are never included in a snapshot; the signing state is
+// node-specific and restoring it elsewhere risks double signing
+var excludedFiles = map[string]bool{
+	"priv_validator_state.json": true,
+}
+
+// Manifest describes a snapshot archive
+type Manifest struct {
+	ChainID   string    `json:"chain_id"`
+	Height    int64     `json:"height"`
+	AppHash   string    `json:"app_hash"`
+	File      string    `json:"file"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Name returns the base file name (without extension) for a snapshot
+func Name(chainID string, height int64, t time.Time) string {
+	return fmt.Sprintf("%s-%d-%s", chainID, height, t.UTC().Format("20060102T150405Z"))
+}
+
+// Create archives dataDir into a gzip-compressed tarball at destPath.
+// Entries are stored under "data/". Returns archive size and sha256 hex digest.
+func Create(dataDir, destPath string) (int64, string, error) {
+	tmpPath := destPath + ".partial"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create %s: %w", tmpPath, err)
+	}
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	gz := gzip.NewWriter(io.MultiWriter(f, hash, counter))
+	tw := tar.NewWriter(gz)
+
+	err = addDir(tw, dataDir)
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, "", fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		os.Remove(tmpPath)
+		return 0, "", fmt.Errorf("failed to finalize archive: %w", err)
+	}
+
+	return counter.n, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func addDir(tw *tar.Writer, dataDir string) error {
+	return filepath.Walk(dataDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if excludedFiles[info.Name()] {
+			return nil
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dataDir, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join("data", rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+}
+
+// WriteManifest writes the manifest next to its archive and updates latest.json
+func WriteManifest(dir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	name := strings.TrimSuffix(m.File, ".tar.gz") + ".json"
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LatestManifest), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", LatestManifest, err)
+	}
+	return nil
+}
+
+// List returns all snapshot manifests in dir, newest first
+func List(dir string) ([]*Manifest, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []*Manifest
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == LatestManifest || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var m Manifest
+		if err := json.Unmarshal(data, &m); err != nil || m.File == "" {
+			continue
+		}
+		manifests = append(manifests, &m)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].CreatedAt.After(manifests[j].CreatedAt)
+	})
+	return manifests, nil
+}
+
+// Prune removes snapshots beyond the newest keep (0 = unlimited) and those
+// older than maxAge (0 = no limit). The newest snapshot is always kept.
+// Returns the archive names that were removed.
+func Prune(dir string, keep int, maxAge time.Duration) ([]string, error) {
+	manifests, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	now := time.Now()
+	for i, m := range manifests {
+		if i == 0 {
+			continue
+		}
+		tooMany := keep > 0 && i >= keep
+		tooOld := maxAge > 0 && now.Sub(m.CreatedAt) > maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, m.File)); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove %s: %w", m.File, err)
+		}
+		manifestPath := filepath.Join(dir, strings.TrimSuffix(m.File, ".tar.gz")+".json")
+		if err := os.Remove(manifestPath); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove %s: %w", manifestPath, err)
+		}
+		removed = append(removed, m.File)
+	}
+	return removed, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,123 @@
+package tmkms
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"text/template"
+
+	"scaller/internal/keys"
+	"scaller/internal/node"
+)
+
+// DefaultHome is where the tmkms image keeps its config, secrets and state
+const DefaultHome = "/tmkms"
+
+// Config holds the values rendered into tmkms.toml
+type Config struct {
+	ChainID         string
+	Addr            string // sekaid priv_validator_laddr as seen from tmkms
+	Home            string // tmkms home inside its container
+	ProtocolVersion string
+}
+
+// Paths of the key files relative to the tmkms home
+const (
+	ConsensusKeyFile = "secrets/priv_validator_key.json"
+	IdentityKeyFile  = "secrets/kms-identity.key"
+)
+
+// StateFile returns the path of the consensus state of chainID relative to the tmkms home
+func StateFile(chainID string) string {
+	return "state/" + chainID + "-consensus.json"
+}
+
+var configTemplate = template.Must(template.New("tmkms.toml").Parse(`# TMKMS configuration for KIRA chain {{ .ChainID }}, generated by scaller tmkms init
+
+## Chain Configuration
+[[chain]]
+id = "{{ .ChainID }}"
+key_format = { type = "bech32", account_key_prefix = "{{ .AccountPrefix }}", consensus_key_prefix = "{{ .ConsensusPrefix }}" }
+state_file = "{{ .Home }}/{{ .StateFile }}"
+
+## Signing Provider Configuration - Software Signer
+[[providers.softsign]]
+chain_ids = ["{{ .ChainID }}"]
+key_type = "consensus"
+path = "{{ .Home }}/{{ .ConsensusKeyFile }}"
+
+## Validator Configuration
+[[validator]]
+chain_id = "{{ .ChainID }}"
+# Address of sekaid's priv_validator_laddr
+addr = "{{ .Addr }}"
+secret_key = "{{ .Home }}/{{ .IdentityKeyFile }}"
+protocol_version = "{{ .ProtocolVersion }}"
+reconnect = true
+`))
+
+// Render returns the tmkms.toml content for c
+func (c Config) Render() ([]byte, error) {
+	var buf bytes.Buffer
+	err := configTemplate.Execute(&buf, struct {
+		Config
+		AccountPrefix, ConsensusPrefix               string
+		StateFile, ConsensusKeyFile, IdentityKeyFile string
+	}{
+		Config:           c,
+		AccountPrefix:    keys.AccountPrefix + "pub",
+		ConsensusPrefix:  keys.ValconsPrefix + "pub",
+		StateFile:        StateFile(c.ChainID),
+		ConsensusKeyFile: ConsensusKeyFile,
+		IdentityKeyFile:  IdentityKeyFile,
+	})
+	return buf.Bytes(), err
+}
+
+// SoftsignKey encodes an ed25519 key as tmkms softsign and identity keys
+// store it: base64 of the 32 byte seed
+func SoftsignKey(k *keys.ConsensusKey) []byte {
+	seed := k.PrivKey().Seed()
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(seed)))
+	base64.StdEncoding.Encode(out, seed)
+	for i := range seed {
+		seed[i] = 0
+	}
+	return out
+}
+
+// State returns s as a tmkms consensus state file, which writes the height
+// and round as strings
+func State(s node.SignState) ([]byte, error) {
+	return json.MarshalIndent(map[string]interface{}{
+		"height":   strconv.FormatInt(s.Height, 10),
+		"round":    strconv.FormatInt(s.Round, 10),
+		"step":     s.Step,
+		"block_id": nil,
+	}, "", "  ")
+}
+
+var cometVersion = regexp.MustCompile(`(?:cometbft/cometbft|tendermint/tendermint)@v0\.(\d+)\.`)
+
+// ProtocolVersion maps the CometBFT or Tendermint version in the output of
+// 'sekaid version --long' to the tmkms protocol_version. CometBFT 0.37 kept
+// the 0.34 privval protocol; 0.38 changed it for vote extensions.
+func ProtocolVersion(versionOutput string) (string, error) {
+	m := cometVersion.FindStringSubmatch(versionOutput)
+	if m == nil {
+		return "", fmt.Errorf("no CometBFT or Tendermint dependency in sekaid version output")
+	}
+	minor, _ := strconv.Atoi(m[1])
+	switch {
+	case minor >= 38:
+		return "v0.38", nil
+	case minor >= 34:
+		return "v0.34", nil
+	case minor == 33:
+		return "v0.33", nil
+	}
+	return "", fmt.Errorf("unsupported CometBFT version 0.%d", minor)
+}
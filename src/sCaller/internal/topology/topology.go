@@ -0,0 +1,229 @@
+package topology
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"scaller/internal/config"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Node roles
+const (
+	RoleValidator = "validator"
+	RoleSentry    = "sentry"
+	RoleSeed      = "seed"
+	RoleArchive   = "archive"
+)
+
+// Roles lists the supported roles
+var Roles = []string{RoleValidator, RoleSentry, RoleSeed, RoleArchive}
+
+// Node is one machine in the topology
+type Node struct {
+	Name    string `toml:"name"`
+	ID      string `toml:"id"`
+	Address string `toml:"address"`
+}
+
+// Peer formats the node as nodeID@host:port
+func (n Node) Peer() string {
+	return n.ID + "@" + n.Address
+}
+
+// Topology lists validators, their sentries and optional seeds
+type Topology struct {
+	Validators []Node `toml:"validator"`
+	Sentries   []Node `toml:"sentry"`
+	Seeds      []Node `toml:"seed"`
+}
+
+// Load reads and validates a topology file
+func Load(path string) (*Topology, error) {
+	var t Topology
+	if _, err := toml.DecodeFile(path, &t); err != nil {
+		return nil, fmt.Errorf("failed to load topology: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, n := range t.All() {
+		if n.Name == "" || n.ID == "" || n.Address == "" {
+			return nil, fmt.Errorf("topology node %q: name, id and address are required", n.Name)
+		}
+		if _, _, err := net.SplitHostPort(n.Address); err != nil {
+			return nil, fmt.Errorf("topology node %s: invalid address %q", n.Name, n.Address)
+		}
+		if seen[n.Name] {
+			return nil, fmt.Errorf("duplicate topology node name %s", n.Name)
+		}
+		seen[n.Name] = true
+	}
+	if len(t.Validators) > 0 && len(t.Sentries) == 0 {
+		return nil, fmt.Errorf("topology has validators but no sentries")
+	}
+	return &t, nil
+}
+
+// All returns every node in the topology
+func (t *Topology) All() []Node {
+	var all []Node
+	all = append(all, t.Validators...)
+	all = append(all, t.Sentries...)
+	all = append(all, t.Seeds...)
+	return all
+}
+
+// RoleOf returns the role of the node with the given name
+func (t *Topology) RoleOf(name string) (string, bool) {
+	return t.roleWhere(func(n Node) bool { return n.Name == name })
+}
+
+// RoleOfID returns the role of the node with the given node ID
+func (t *Topology) RoleOfID(id string) (string, bool) {
+	return t.roleWhere(func(n Node) bool { return n.ID == id })
+}
+
+func (t *Topology) roleWhere(match func(Node) bool) (string, bool) {
+	for role, nodes := range map[string][]Node{RoleValidator: t.Validators, RoleSentry: t.Sentries, RoleSeed: t.Seeds} {
+		for _, n := range nodes {
+			if match(n) {
+				return role, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Settings returns the p2p, pruning and indexer overrides for role
+func Settings(role string) (config.ScallConfig, error) {
+	s := make(config.ScallConfig)
+	switch role {
+	case RoleValidator:
+		// Only talk to own sentries; never gossip the validator's address
+		s.SetValue("config.p2p.pex", false)
+		s.SetValue("config.p2p.seed_mode", false)
+		s.SetValue("config.p2p.addr_book_strict", false)
+		s.SetValue("config.tx_index.indexer", "null")
+	case RoleSentry:
+		s.SetValue("config.p2p.pex", true)
+		s.SetValue("config.p2p.seed_mode", false)
+		s.SetValue("config.p2p.addr_book_strict", false)
+		s.SetValue("config.tx_index.indexer", "kv")
+	case RoleSeed:
+		s.SetValue("config.p2p.pex", true)
+		s.SetValue("config.p2p.seed_mode", true)
+		s.SetValue("config.p2p.addr_book_strict", true)
+		s.SetValue("config.tx_index.indexer", "null")
+	case RoleArchive:
+		s.SetValue("config.p2p.pex", true)
+		s.SetValue("config.p2p.seed_mode", false)
+		s.SetValue("config.tx_index.indexer", "kv")
+		s.SetValue("app.pruning", "nothing")
+	default:
+		return nil, fmt.Errorf("unknown role %q (use %s)", role, strings.Join(Roles, "|"))
+	}
+	return s, nil
+}
+
+// Links describes the peer lists a node gets from the topology
+type Links struct {
+	Seeds         []Node
+	Persistent    []Node
+	Private       []string
+	Unconditional []string
+}
+
+// LinksFor returns the topology peers for a node of role, excluding selfID
+func (t *Topology) LinksFor(role, selfID string) Links {
+	var l Links
+	others := func(nodes []Node) []Node {
+		var out []Node
+		for _, n := range nodes {
+			if n.ID != selfID {
+				out = append(out, n)
+			}
+		}
+		return out
+	}
+
+	switch role {
+	case RoleValidator:
+		l.Persistent = others(t.Sentries)
+		l.Unconditional = ids(l.Persistent)
+	case RoleSentry:
+		validators := others(t.Validators)
+		l.Seeds = others(t.Seeds)
+		l.Persistent = append(validators, others(t.Sentries)...)
+		l.Private = ids(validators)
+		l.Unconditional = ids(validators)
+	case RoleSeed, RoleArchive:
+		l.Seeds = others(t.Seeds)
+		l.Persistent = others(t.Sentries)
+	}
+	return l
+}
+
+// Apply writes the links into s as comma-separated p2p lists. Existing
+// persistent peers and seeds in s are kept.
+func (l Links) Apply(s config.ScallConfig) {
+	merge := func(key string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		if existing, ok := s[key].(string); ok && existing != "" {
+			values = append(strings.Split(existing, ","), values...)
+		}
+		s.SetValue(key, strings.Join(dedupe(values), ","))
+	}
+
+	merge("config.p2p.seeds", peerStrings(l.Seeds))
+	merge("config.p2p.persistent_peers", peerStrings(l.Persistent))
+	merge("config.p2p.private_peer_ids", l.Private)
+	merge("config.p2p.unconditional_peer_ids", l.Unconditional)
+}
+
+// Generate returns the scall overrides for every node in the topology, keyed by node name
+func (t *Topology) Generate() (map[string]config.ScallConfig, error) {
+	result := make(map[string]config.ScallConfig)
+	for _, n := range t.All() {
+		role, _ := t.RoleOf(n.Name)
+		s, err := Settings(role)
+		if err != nil {
+			return nil, err
+		}
+		t.LinksFor(role, n.ID).Apply(s)
+		result[n.Name] = s
+	}
+	return result, nil
+}
+
+func ids(nodes []Node) []string {
+	out := make([]string, len(nodes))
+	for i, n := range nodes {
+		out[i] = n.ID
+	}
+	return out
+}
+
+func peerStrings(nodes []Node) []string {
+	out := make([]string, len(nodes))
+	for i, n := range nodes {
+		out[i] = n.Peer()
+	}
+	return out
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}